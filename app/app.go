@@ -15,12 +15,98 @@ type ConfigType struct {
 	Anchor       Anchor       `yaml:"Anchor"`
 	PublicChain  PublicChain  `yaml:"PublicChain"`
 	PrivateChain PrivateChain `yaml:"PrivateChain"`
+	Audit        Audit        `yaml:"Audit"`
+}
+
+// The audit verifies recorded block info against the private chain continuously without the anchoring key.
+// Recent batches are verified as soon as they are recorded, and random historical heights are verified periodically.
+// Missing and failed heights of recent batches are verified again until the recheck timeout before they are reported.
+type Audit struct {
+	RecentPeriod      int     `yaml:"RecentPeriod"`
+	RecheckTimeout    int     `yaml:"RecheckTimeout"`
+	HistoricalPeriod  int     `yaml:"HistoricalPeriod"`
+	HistoricalSample  int     `yaml:"HistoricalSample"`
+	Concurrency       int     `yaml:"Concurrency"`
+	Rate              float64 `yaml:"Rate"`
+	MetricsListenAddr string  `yaml:"MetricsListenAddr"`
 }
 
 type Anchor struct {
-	CollectBlockCount int `yaml:"CollectBlockCount"`
-	RequestPeriod     int `yaml:"RequestPeriod"`
-	DB                DB  `yaml:"DB"`
+	CollectBlockCount   int                 `yaml:"CollectBlockCount"`
+	RequestPeriod       int                 `yaml:"RequestPeriod"`
+	BatchQueueSize      int                 `yaml:"BatchQueueSize"`
+	MaxInFlightTx       int                 `yaml:"MaxInFlightTx"`
+	TxConfirmTimeout    int                 `yaml:"TxConfirmTimeout"`
+	MaxTxBytes          int                 `yaml:"MaxTxBytes"`
+	MaxTxGas            uint64              `yaml:"MaxTxGas"`
+	Accounts            []string            `yaml:"Accounts"`
+	AccountReportPeriod int                 `yaml:"AccountReportPeriod"`
+	GapCheckPeriod      int                 `yaml:"GapCheckPeriod"`
+	GapCheckWindow      int                 `yaml:"GapCheckWindow"`
+	RateLimit           RateLimit           `yaml:"RateLimit"`
+	Sink                string              `yaml:"Sink"`
+	Memo                Memo                `yaml:"Memo"`
+	MaintenanceWindows  []MaintenanceWindow `yaml:"MaintenanceWindows"`
+	Admin               Admin               `yaml:"Admin"`
+	Supervisor          Supervisor          `yaml:"Supervisor"`
+	Authz               Authz               `yaml:"Authz"`
+	HA                  HA                  `yaml:"HA"`
+	DB                  DB                  `yaml:"DB"`
+}
+
+// In the memo sink, the commitment of the batch is written to the memo of the bank self-send transaction
+// instead of executing the anchor contract.
+type Memo struct {
+	IndexFile string `yaml:"IndexFile"`
+	Amount    string `yaml:"Amount"`
+}
+
+// The gateway is paused automatically in the window.
+// Start and end are RFC3339 times, or daily times such as "15:04" in UTC.
+type MaintenanceWindow struct {
+	Start string `yaml:"Start"`
+	End   string `yaml:"End"`
+}
+
+// The admin HTTP endpoint to check the status, and pause or resume the gateway.
+// If the token is set, requests must have it as the bearer token.
+type Admin struct {
+	ListenAddr string `yaml:"ListenAddr"`
+	Token      string `yaml:"Token"`
+}
+
+// Failed components of the gateway are restarted with the backoff.
+// If they are restarted more than the max restarts in the window (milliseconds), the gateway stops.
+type Supervisor struct {
+	MaxRestarts   int `yaml:"MaxRestarts"`
+	RestartWindow int `yaml:"RestartWindow"`
+}
+
+// In the authz mode, the granter is the owner of the anchor contract,
+// and the accounts of the gateway send anchoring messages on behalf of the granter.
+// The fee granter pays fees of submitters which send anchoring messages by themselves, and it is not used in the authz mode.
+type Authz struct {
+	Granter    string `yaml:"Granter"`
+	FeeGrant   bool   `yaml:"FeeGrant"`
+	FeeGranter string `yaml:"FeeGranter"`
+}
+
+// Requests to the private chain are limited by the token bucket.
+// The rate is the number of requests per second, and the burst is the size of the bucket.
+type RateLimit struct {
+	Rate       float64 `yaml:"Rate"`
+	Burst      int     `yaml:"Burst"`
+	MaxBackoff int     `yaml:"MaxBackoff"`
+}
+
+// In the high availability mode, only the instance which holds the leader lease runs the gateway.
+// The lease is recorded in the MySQL database or the lock file on the shared storage.
+type HA struct {
+	Backend       string `yaml:"Backend"`
+	LockFile      string `yaml:"LockFile"`
+	LeaseDuration int    `yaml:"LeaseDuration"`
+	RenewPeriod   int    `yaml:"RenewPeriod"`
+	InstanceID    string `yaml:"InstanceID"`
 }
 
 type DB struct {
@@ -38,16 +124,42 @@ type Contract struct {
 }
 
 type PublicChain struct {
-	ChainID       string `yaml:"ChainID"`
-	LCD           string `yaml:"LCD"`
-	GasAdj        string `yaml:"GasAdj"`
-	GasLimit      string `yaml:"GasLimit"`
-	BroadcastMode string `yaml:"BroadcastMode"`
+	ChainID       string    `yaml:"ChainID"`
+	LCD           string    `yaml:"LCD"`
+	LCDs          []string  `yaml:"LCDs"`
+	GasAdj        string    `yaml:"GasAdj"`
+	GasLimit      string    `yaml:"GasLimit"`
+	BroadcastMode string    `yaml:"BroadcastMode"`
+	Transport     Transport `yaml:"Transport"`
 }
 
 type PrivateChain struct {
-	ChainID string `yaml:"ChainID"`
-	LCD     string `yaml:"LCD"`
+	ChainID           string    `yaml:"ChainID"`
+	LCD               string    `yaml:"LCD"`
+	LCDs              []string  `yaml:"LCDs"`
+	HealthCheckPeriod int       `yaml:"HealthCheckPeriod"`
+	MaxLagBlocks      int       `yaml:"MaxLagBlocks"`
+	RequireAgreement  bool      `yaml:"RequireAgreement"`
+	BlockAdapter      string    `yaml:"BlockAdapter"`
+	FinalityDepth     int       `yaml:"FinalityDepth"`
+	Transport         Transport `yaml:"Transport"`
+}
+
+// Settings of TLS, authentication and proxy to request the LCD.
+// TLS verification is on by default.
+type Transport struct {
+	CAFile             string    `yaml:"CAFile"`
+	CertFile           string    `yaml:"CertFile"`
+	KeyFile            string    `yaml:"KeyFile"`
+	InsecureSkipVerify bool      `yaml:"InsecureSkipVerify"`
+	BearerToken        string    `yaml:"BearerToken"`
+	BasicAuth          BasicAuth `yaml:"BasicAuth"`
+	Proxy              string    `yaml:"Proxy"`
+}
+
+type BasicAuth struct {
+	UserName string `yaml:"UserName"`
+	Password string `yaml:"Password"`
 }
 
 // Generate default app.yaml.
@@ -1,7 +1,10 @@
 package app
 
 import (
+	"errors"
 	"os"
+	"reflect"
+	"strings"
 	"sync"
 
 	"gopkg.in/yaml.v3"
@@ -11,6 +14,7 @@ var once sync.Once
 var instance *singleton
 
 type singleton struct {
+	mu  sync.RWMutex
 	app AppType
 }
 
@@ -23,6 +27,9 @@ func AppFile() *singleton {
 }
 
 func (s *singleton) Get() AppType {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	return s.app
 }
 
@@ -31,11 +38,74 @@ func (s *singleton) Read(filePath string) error {
 	if err != nil {
 		return err
 	}
+
+	s.mu.Lock()
 	s.app = *appType
+	s.mu.Unlock()
 
 	return nil
 }
 
+// Read app.yaml again while the gateway runs.
+// If fields which need the restart are changed, the file is rejected and the current config is kept.
+func (s *singleton) Reload(filePath string) error {
+	appType, err := readAppFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if appType.Config.PublicChain.LCD == "" || appType.Config.PrivateChain.LCD == "" {
+		return errors.New("the config must include LCD URL")
+	}
+
+	if appType.Config.Anchor.CollectBlockCount <= 0 {
+		return errors.New("collect block count must be bigger than 0")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []string
+	for _, field := range restartFields {
+		if !reflect.DeepEqual(field.value(s.app), field.value(*appType)) {
+			changed = append(changed, field.name)
+		}
+	}
+
+	if len(changed) != 0 {
+		return errors.New("changes of " + strings.Join(changed, ", ") + " need the restart of the gateway")
+	}
+
+	s.app = *appType
+
+	return nil
+}
+
+// Fields which are applied only when the gateway starts.
+var restartFields = []struct {
+	name  string
+	value func(AppType) interface{}
+}{
+	{"Home", func(a AppType) interface{} { return a.Home }},
+	{"Contract", func(a AppType) interface{} { return a.Contract }},
+	{"Anchor.Accounts", func(a AppType) interface{} { return a.Config.Anchor.Accounts }},
+	{"Anchor.BatchQueueSize", func(a AppType) interface{} { return a.Config.Anchor.BatchQueueSize }},
+	{"Anchor.MaxInFlightTx", func(a AppType) interface{} { return a.Config.Anchor.MaxInFlightTx }},
+	{"Anchor.Sink", func(a AppType) interface{} { return a.Config.Anchor.Sink }},
+	{"Anchor.Memo.IndexFile", func(a AppType) interface{} { return a.Config.Anchor.Memo.IndexFile }},
+	{"Anchor.Admin", func(a AppType) interface{} { return a.Config.Anchor.Admin }},
+	{"Anchor.Supervisor", func(a AppType) interface{} { return a.Config.Anchor.Supervisor }},
+	{"Anchor.Authz", func(a AppType) interface{} { return a.Config.Anchor.Authz }},
+	{"Anchor.HA", func(a AppType) interface{} { return a.Config.Anchor.HA }},
+	{"Anchor.DB", func(a AppType) interface{} { return a.Config.Anchor.DB }},
+	{"PublicChain.ChainID", func(a AppType) interface{} { return a.Config.PublicChain.ChainID }},
+	{"PublicChain.Transport", func(a AppType) interface{} { return a.Config.PublicChain.Transport }},
+	{"PrivateChain.ChainID", func(a AppType) interface{} { return a.Config.PrivateChain.ChainID }},
+	{"PrivateChain.RequireAgreement", func(a AppType) interface{} { return a.Config.PrivateChain.RequireAgreement }},
+	{"PrivateChain.BlockAdapter", func(a AppType) interface{} { return a.Config.PrivateChain.BlockAdapter }},
+	{"PrivateChain.Transport", func(a AppType) interface{} { return a.Config.PrivateChain.Transport }},
+}
+
 func readAppFile(filePath string) (*AppType, error) {
 	yamlFile, err := os.ReadFile(filePath)
 	if err != nil {
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -34,15 +35,21 @@ func balance(a *types.App) *cobra.Command {
 		Example: strings.TrimSpace(fmt.Sprintf(`
 $ %s query account balance
 $ %s q acc balance
-		`, defaultAppName, defaultAppName)),
+$ %s q acc balance --name [key_name]
+		`, defaultAppName, defaultAppName, defaultAppName)),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			home, err := cmd.Flags().GetString(flagHome)
 			if err != nil {
 				return util.LogErr(types.ErrAccount, err)
 			}
 
+			name, err := cmd.Flags().GetString(flagKeyName)
+			if err != nil {
+				return util.LogErr(types.ErrAccount, err)
+			}
+
 			util.LogInfo(util.Y("(need passphrase. not tx, only using gen address from the key)"))
-			_, addr, err := extractKey(home)
+			_, addr, err := extractKeyByName(home, name)
 			if err != nil {
 				return util.LogErr(types.ErrAccount, err)
 			}
@@ -56,12 +63,25 @@ $ %s q acc balance
 				return util.LogErr(types.ErrAccount, err)
 			}
 
-			util.LogInfo(res)
-			util.LogInfo(util.BB("response data successfully"))
+			var response types.QueryBalancesResponse
+			err = json.Unmarshal([]byte(res), &response)
+			if err != nil {
+				return util.LogErr(types.ErrAccount, err)
+			}
+
+			output := types.AccountBalancesOutput{Address: addr, Balances: append([]types.Coin{}, response.Balances...)}
+			err = printOutput(a, output, func() {
+				util.LogInfo(res)
+				util.LogInfo(util.BB("response data successfully"))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrAccount, err)
+			}
 			return nil
 
 		},
 	}
+	cmd.Flags().String(flagKeyName, defaultKeyName, "name of the key")
 
 	return cmd
 }
@@ -75,15 +95,21 @@ func info(a *types.App) *cobra.Command {
 		Example: strings.TrimSpace(fmt.Sprintf(`
 $ %s query account info
 $ %s q acc info
-		`, defaultAppName, defaultAppName)),
+$ %s q acc info --name [key_name]
+		`, defaultAppName, defaultAppName, defaultAppName)),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			home, err := cmd.Flags().GetString(flagHome)
 			if err != nil {
 				return util.LogErr(types.ErrAccount, err)
 			}
 
+			name, err := cmd.Flags().GetString(flagKeyName)
+			if err != nil {
+				return util.LogErr(types.ErrAccount, err)
+			}
+
 			util.LogInfo(util.Y("(need passphrase. not tx, only using gen address from the key)"))
-			_, addr, err := extractKey(home)
+			_, addr, err := extractKeyByName(home, name)
 			if err != nil {
 				return util.LogErr(types.ErrAccount, err)
 			}
@@ -97,12 +123,27 @@ $ %s q acc info
 				return util.LogErr(types.ErrAccount, err)
 			}
 
-			util.LogInfo(res)
-			util.LogInfo(util.BB("response data successfully"))
+			var response struct {
+				Account json.RawMessage `json:"account"`
+			}
+			err = json.Unmarshal([]byte(res), &response)
+			if err != nil {
+				return util.LogErr(types.ErrAccount, err)
+			}
+
+			output := types.AccountInfoOutput{Address: addr, Account: response.Account}
+			err = printOutput(a, output, func() {
+				util.LogInfo(res)
+				util.LogInfo(util.BB("response data successfully"))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrAccount, err)
+			}
 			return nil
 
 		},
 	}
+	cmd.Flags().String(flagKeyName, defaultKeyName, "name of the key")
 
 	return cmd
 }
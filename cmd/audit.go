@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw"
+	"github.com/Moonyongjung/xpla-anchor/gw/db"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/spf13/cobra"
+)
+
+const auditDir = "audit"
+
+// Audit recorded block info continuously.
+// The audit does not use the private key, so it runs alongside the gateway independently of the anchoring process.
+func AuditCmd(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "audit recorded block info against the private chain continuously",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s audit
+$ %s audit --from [start_height]
+$ %s audit --address [contract_address] --priv-block-api [blockinfo_api_of_private_chain]
+$ %s audit --log db
+		`, defaultAppName, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, err := cmd.Flags().GetString(flagHome)
+			if err != nil {
+				return util.LogErr(types.ErrParseConfig, err)
+			}
+
+			appFilePath, err := getAppFile(home)
+			if err != nil {
+				return util.LogErr(types.ErrParseApp, err)
+			}
+
+			err = loadMemoIndex(home)
+			if err != nil {
+				return util.LogErr(types.ErrParseConfig, err)
+			}
+
+			pubClient, privClient, err := initXplaClient(home, false)
+			if err != nil {
+				return err
+			}
+
+			a.PubClient = pubClient
+			a.PrivClient = privClient
+			a.AppFilePath = appFilePath
+
+			addr, err := cmd.Flags().GetString(flagContractAddr)
+			if err != nil {
+				return util.LogErr(types.ErrAudit, err)
+			}
+
+			if addr == "" {
+				addr = app.AppFile().Get().Contract.Address
+			}
+
+			blockApi, err := cmd.Flags().GetString(flagPrivBlockApi)
+			if err != nil {
+				return util.LogErr(types.ErrAudit, err)
+			}
+
+			log, err := cmd.Flags().GetString(flagLog)
+			if err != nil {
+				return util.LogErr(types.ErrAudit, err)
+			}
+
+			if !(log == defaultLog || log == dbLog) {
+				return util.LogErr(types.ErrAudit, "invalid log type")
+			}
+
+			// Logs and inconsistent results are saved to DB.
+			if log == dbLog {
+				err = db.DbInit()
+				if err != nil {
+					return util.LogErr(types.ErrAudit, err)
+				}
+
+				auditDb, err := db.AuditDbInit()
+				if err != nil {
+					return util.LogErr(types.ErrAudit, err)
+				}
+				gw.AuditMng().UseDb(auditDb)
+			}
+
+			fromHeight, err := cmd.Flags().GetString(flagFromHeight)
+			if err != nil {
+				return util.LogErr(types.ErrAudit, err)
+			}
+
+			// Recent batches are audited from the progress of the previous run or the recorded latest height by default.
+			if fromHeight != "" {
+				from, err := strconv.ParseUint(fromHeight, 10, 64)
+				if err != nil || from == 0 {
+					return util.LogErr(types.ErrAudit, "invalid block height "+fromHeight)
+				}
+			}
+
+			// The progress of the audit is kept for each contract.
+			progressFile := path.Join(home, auditDir, addr+".json")
+
+			auditErr := make(chan error, 1)
+			go func() {
+				auditErr <- gw.StartAudit(a, addr, blockApi, fromHeight, progressFile)
+			}()
+
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+			select {
+			case <-stop:
+				util.LogInfo("audit stopped")
+				return nil
+
+			case err := <-auditErr:
+				return util.LogErr(types.ErrAudit, "audit stopped:", err)
+			}
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
+	cmd.Flags().String(flagLog, defaultLog, "select log type")
+	cmd.Flags().String(flagFromHeight, "", "start block height of recent batches to audit (default: next of the audited height of the previous run or the recorded latest height)")
+
+	return cmd
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/key"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	executeContractMsgType = "/cosmwasm.wasm.v1.MsgExecuteContract"
+	authzExecMsgType       = "/cosmos.authz.v1beta1.MsgExec"
+	defaultGrantPeriod     = time.Hour * 24 * 365
+)
+
+// Grant the account of the gateway to send anchoring messages.
+// The recommended grant is the fee allowance to the submitter of the anchor contract. The submitter sends anchoring messages by itself,
+// and the owner (the default key) only pays fees, which are limited by the spend limit.
+// The wasm module of the public chain does not support the authorization which is scoped to the contract,
+// so the generic authorization of MsgExecuteContract is unsafe, and it is only granted when the owner accepts its scope.
+func grant(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grant",
+		Short: "grant the grantee to send anchoring messages",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s execute grant --grantee [submitter_address] --fee-grant --spend-limit [amount] --expiration [RFC3339_time]
+$ %s e grant --grantee [grantee_address] --accept-generic
+$ %s e grant --grantee [grantee_address] --accept-generic --fee-grant --spend-limit [amount] --expiration [RFC3339_time]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			grantee, err := cmd.Flags().GetString(flagGrantee)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			if grantee == "" {
+				return util.LogErr(types.ErrAuthz, "grantee address is mandatory")
+			}
+
+			acceptGeneric, err := cmd.Flags().GetBool(flagAcceptGeneric)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			isFeeGrant, err := cmd.Flags().GetBool(flagFeeGrant)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			if !acceptGeneric && !isFeeGrant {
+				util.LogWarning("add the grantee as the submitter of the anchor contract, and grant the fee allowance by --" + flagFeeGrant)
+				return util.LogErr(types.ErrAuthz, "nothing is granted, set --"+flagFeeGrant+" for the submitter, or --"+flagAcceptGeneric+" to grant the generic authorization which is unsafe")
+			}
+
+			spendLimit, err := cmd.Flags().GetString(flagSpendLimit)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			expiration, err := cmd.Flags().GetString(flagExpiration)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			expireTime := time.Now().Add(defaultGrantPeriod)
+			if expiration != "" {
+				expireTime, err = time.Parse(time.RFC3339, expiration)
+				if err != nil {
+					return util.LogErr(types.ErrAuthz, err)
+				}
+			}
+
+			granter, err := key.Bech32AddrString(a.PubClient.GetPrivateKey())
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			// The submitter sends anchoring messages by itself, so the fee allowance is only used by MsgExecuteContract.
+			if !acceptGeneric {
+				err = grantFeeAllowance(a, granter, grantee, spendLimit, expireTime, executeContractMsgType)
+				if err != nil {
+					return util.LogErr(types.ErrAuthz, err)
+				}
+
+				util.LogInfo("add the grantee as the submitter of the anchor contract, and set Authz.FeeGranter as " + granter)
+				return nil
+			}
+
+			warnGenericGrant(grantee)
+
+			authzGrantMsg := xtypes.AuthzGrantMsg{
+				Granter:           granter,
+				Grantee:           grantee,
+				AuthorizationType: "generic",
+				MsgType:           executeContractMsgType,
+				Expiration:        util.ToString(expireTime.Unix(), ""),
+			}
+
+			txByte, err := a.PubClient.AuthzGrant(authzGrantMsg).CreateAndSignTx()
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			util.LogWait("send tx to grant execute contract...")
+			res, err := a.PubClient.Broadcast(txByte)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			util.LogInfo(res.Response)
+			util.LogInfo(util.BB("grant execute contract successfully"))
+
+			if !isFeeGrant {
+				return nil
+			}
+
+			// The fee allowance is only used by anchoring messages which are wrapped in MsgExec.
+			nextSequence(a)
+			err = grantFeeAllowance(a, granter, grantee, spendLimit, expireTime, authzExecMsgType)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagGrantee, "", "address of the grantee which sends anchoring messages")
+	cmd.Flags().Bool(flagFeeGrant, false, "grant the fee allowance to the grantee")
+	cmd.Flags().String(flagSpendLimit, "", "spend limit of the fee allowance (axpla)")
+	cmd.Flags().String(flagExpiration, "", "expiration time of grants by RFC3339 (default: after one year)")
+	cmd.Flags().Bool(flagAcceptGeneric, false, "grant the generic authorization of MsgExecuteContract, which is unsafe because it is not limited to the anchor contract")
+
+	return cmd
+}
+
+// Grant the fee allowance which is only used by the message type.
+func grantFeeAllowance(a *types.App, granter, grantee, spendLimit string, expireTime time.Time, allowedMsg string) error {
+	feeGrantMsg := xtypes.FeeGrantMsg{
+		Granter:    granter,
+		Grantee:    grantee,
+		SpendLimit: spendLimit,
+		Expiration: expireTime.UTC().Format(time.RFC3339),
+		AllowedMsg: []string{allowedMsg},
+	}
+
+	txByte, err := a.PubClient.FeeGrant(feeGrantMsg).CreateAndSignTx()
+	if err != nil {
+		return err
+	}
+
+	util.LogWait("send tx to grant fee allowance...")
+	res, err := a.PubClient.Broadcast(txByte)
+	if err != nil {
+		return err
+	}
+
+	util.LogInfo(res.Response)
+	util.LogInfo(util.BB("grant fee allowance successfully"))
+
+	return nil
+}
+
+// Print the scope of the generic authorization.
+// It is not limited to the anchor contract, so the grantee can execute any contract as the owner.
+func warnGenericGrant(grantee string) {
+	util.LogWarning(util.R("the generic authorization of MsgExecuteContract is granted to " + grantee + ", it is unsafe"))
+	util.LogWarning("the grantee is able to execute any contract as the owner, not only the anchor contract")
+	util.LogWarning("the grantee is able to attach funds of the owner to executions of contracts")
+	util.LogWarning("the grantee is able to execute owner messages of the anchor contract, such as update_submitters")
+	util.LogWarning("keep funds of the owner low and revoke grants as soon as the grantee key is compromised")
+	util.LogWarning("the submitter with the fee allowance is recommended instead, grant it without --" + flagAcceptGeneric)
+}
+
+// Revoke grants of the grantee.
+func revoke(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "revoke the grantee to send anchoring messages",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s execute revoke --grantee [submitter_address] --fee-only
+$ %s e revoke --grantee [grantee_address]
+$ %s e revoke --grantee [grantee_address] --fee-grant
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			grantee, err := cmd.Flags().GetString(flagGrantee)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			if grantee == "" {
+				return util.LogErr(types.ErrAuthz, "grantee address is mandatory")
+			}
+
+			isFeeGrant, err := cmd.Flags().GetBool(flagFeeGrant)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			isFeeOnly, err := cmd.Flags().GetBool(flagFeeOnly)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			granter, err := key.Bech32AddrString(a.PubClient.GetPrivateKey())
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			// The submitter only has the fee allowance.
+			if isFeeOnly {
+				err = revokeFeeAllowance(a, granter, grantee)
+				if err != nil {
+					return util.LogErr(types.ErrAuthz, err)
+				}
+				return nil
+			}
+
+			authzRevokeMsg := xtypes.AuthzRevokeMsg{
+				Granter: granter,
+				Grantee: grantee,
+				MsgType: executeContractMsgType,
+			}
+
+			txByte, err := a.PubClient.AuthzRevoke(authzRevokeMsg).CreateAndSignTx()
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			util.LogWait("send tx to revoke execute contract...")
+			res, err := a.PubClient.Broadcast(txByte)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			util.LogInfo(res.Response)
+			util.LogInfo(util.BB("revoke execute contract successfully"))
+
+			if !isFeeGrant {
+				return nil
+			}
+
+			nextSequence(a)
+			err = revokeFeeAllowance(a, granter, grantee)
+			if err != nil {
+				return util.LogErr(types.ErrAuthz, err)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagGrantee, "", "address of the grantee which sends anchoring messages")
+	cmd.Flags().Bool(flagFeeGrant, false, "revoke the fee allowance of the grantee")
+	cmd.Flags().Bool(flagFeeOnly, false, "only revoke the fee allowance of the submitter")
+
+	return cmd
+}
+
+func revokeFeeAllowance(a *types.App, granter, grantee string) error {
+	revokeFeeGrantMsg := xtypes.RevokeFeeGrantMsg{
+		Granter: granter,
+		Grantee: grantee,
+	}
+
+	txByte, err := a.PubClient.RevokeFeeGrant(revokeFeeGrantMsg).CreateAndSignTx()
+	if err != nil {
+		return err
+	}
+
+	util.LogWait("send tx to revoke fee allowance...")
+	res, err := a.PubClient.Broadcast(txByte)
+	if err != nil {
+		return err
+	}
+
+	util.LogInfo(res.Response)
+	util.LogInfo(util.BB("revoke fee allowance successfully"))
+
+	return nil
+}
+
+// Increase the sequence of the XPLA client in order to send the next transaction
+// before the previous one is included in a block.
+func nextSequence(a *types.App) {
+	seq := util.FromStringToUint64(a.PubClient.GetSequence())
+	a.PubClient.WithSequence(util.FromUint64ToString(seq + 1))
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/spf13/cobra"
+)
+
+const (
+	backfillCmdName = "backfill"
+	backfillDir     = "backfill"
+)
+
+// Anchor blocks of the explicit range of heights.
+// The backfill is used to anchor old blocks after the incident or onboarding the old chain.
+// The progress is saved in the home directory, so the same command resumes the interrupted backfill.
+func BackfillCmd(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   backfillCmdName,
+		Short: "anchor blocks of the range of heights",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s execute backfill --from [start_height] --to [end_height]
+$ %s e backfill --from [start_height] --to [end_height] --address [contract_address]
+$ %s e backfill --from [start_height] --to [end_height] --priv-block-api [blockinfo_api_of_private_chain]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString(flagContractAddr)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			if addr == "" {
+				addr = app.AppFile().Get().Contract.Address
+			}
+
+			blockApi, err := cmd.Flags().GetString(flagPrivBlockApi)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			fromHeight, toHeight, err := heightRangeFlags(cmd)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			if fromHeight == "" || toHeight == "" {
+				return util.LogErr(types.ErrGw, "start and end heights are mandatory")
+			}
+
+			home, err := cmd.Flags().GetString(flagHome)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			senders, err := loadSenders(a, home)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+			a.Senders = senders
+
+			progressFile := path.Join(home, backfillDir, fromHeight+"-"+toHeight+".json")
+
+			// Components are stopped by the interrupt, and the same command resumes from the confirmed height.
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			err = gw.StartBackfill(ctx, a, addr, blockApi, fromHeight, toHeight, progressFile)
+			if errors.Is(err, context.Canceled) {
+				util.LogInfo("backfill stopped")
+				return nil
+			}
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
+	cmd.Flags().String(flagFromHeight, "", "start block height of the backfill")
+	cmd.Flags().String(flagToHeight, "", "end block height of the backfill")
+
+	return cmd
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -29,6 +30,7 @@ func EContractCmd(a *types.App) *cobra.Command {
 	cmd.AddCommand(
 		storeContract(a),
 		instantiateContract(a),
+		updateSubmitters(a),
 	)
 	return cmd
 }
@@ -44,6 +46,7 @@ func QContractCmd(a *types.App) *cobra.Command {
 	cmd.AddCommand(
 		queryContractLatestBlock(a),
 		queryContractBlockData(a),
+		queryContractConfig(a),
 	)
 	return cmd
 }
@@ -164,6 +167,79 @@ $ %s e ctrt inst --code-id [code_id_of_contract]
 	return cmd
 }
 
+// Add or remove the accounts which are able to send anchoring transactions.
+// Only the owner of the anchor contract can update submitters.
+func updateSubmitters(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "submitters",
+		Short: "update submitters of the anchor contract",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s execute contract submitters --add [address1,address2]
+$ %s e ctrt submitters --remove [address]
+$ %s e ctrt submitters --add [address] --address [contract_address]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString(flagContractAddr)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			// If the address flag is not exist, use the contract address is saved in the app.yaml.
+			if addr == "" {
+				addr = app.AppFile().Get().Contract.Address
+			}
+
+			add, err := cmd.Flags().GetStringSlice(flagSubmitterAdd)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			remove, err := cmd.Flags().GetStringSlice(flagSubmitterRemove)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			if len(add) == 0 && len(remove) == 0 {
+				return util.LogErr(types.ErrContract, "no submitters to add or remove")
+			}
+
+			bytes, err := util.JsonMarshalData(types.NewUpdateSubmitters(add, remove))
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			// Generate the execute message.
+			msg := xtypes.ExecuteMsg{
+				ContractAddress: addr,
+				Amount:          "0",
+				ExecMsg:         `{"update_submitters":` + string(bytes) + `}`,
+			}
+			txByte, err := a.PubClient.ExecuteContract(msg).CreateAndSignTx()
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			util.LogWait("send tx to update submitters...")
+			// Broadcast.
+			res, err := a.PubClient.Broadcast(txByte)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			util.LogInfo(res.Response)
+			util.LogInfo(util.BB("update submitters successfully"))
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().StringSlice(flagSubmitterAdd, nil, "addresses to add as submitters")
+	cmd.Flags().StringSlice(flagSubmitterRemove, nil, "addresses to remove from submitters")
+
+	return cmd
+}
+
 // Query the latest block height is recorded in the anchor contract.
 func queryContractLatestBlock(a *types.App) *cobra.Command {
 	cmd := &cobra.Command{
@@ -198,8 +274,20 @@ $ %s q ctrt latest --address [contract_address]
 				return util.LogErr(types.ErrContract, err)
 			}
 
-			util.LogInfo(res)
-			util.LogInfo(util.BB("response data successfully"))
+			var response types.QueryLatestBlockResponse
+			err = json.Unmarshal([]byte(res), &response)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			output := types.LatestBlockOutput{LatestHeight: response.Data.LatestHeight}
+			err = printOutput(a, output, func() {
+				util.LogInfo(res)
+				util.LogInfo(util.BB("response data successfully"))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
 			return nil
 
 		},
@@ -245,8 +333,76 @@ $ %s q ctrt latest [height] --address [contract_address]
 				return util.LogErr(types.ErrContract, err)
 			}
 
-			util.LogInfo(res)
-			util.LogInfo(util.BB("response data successfully"))
+			var response types.QueryBlockInfoResponse
+			err = json.Unmarshal([]byte(res), &response)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			output := types.NewData(response.Data.Height, response.Data.BlockHash, response.Data.DataMerkle, response.Data.Timestamp)
+			err = printOutput(a, output, func() {
+				util.LogInfo(res)
+				util.LogInfo(util.BB("response data successfully"))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+			return nil
+
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+
+	return cmd
+}
+
+// Query the owner and the submitters of the anchor contract.
+func queryContractConfig(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "query the owner and the submitters of the anchor contract",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s query contract config
+$ %s q ctrt config
+$ %s q ctrt config --address [contract_address]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString(flagContractAddr)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			// If the address flag is not exist, use the contract address is saved in the app.yaml.
+			if addr == "" {
+				addr = app.AppFile().Get().Contract.Address
+			}
+
+			// Generate the query message.
+			queryMsg := xtypes.QueryMsg{
+				ContractAddress: addr,
+				QueryMsg:        types.QueryConfigMsg,
+			}
+
+			// Request query by using XPLA client.
+			res, err := a.PubClient.QueryContract(queryMsg).Query()
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			var response types.QueryConfigResponse
+			err = json.Unmarshal([]byte(res), &response)
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
+
+			err = printOutput(a, response.Data, func() {
+				util.LogInfo(res)
+				util.LogInfo(util.BB("response data successfully"))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrContract, err)
+			}
 			return nil
 
 		},
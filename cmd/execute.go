@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"github.com/Moonyongjung/xpla-anchor/app"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
 	"github.com/spf13/cobra"
@@ -24,11 +25,19 @@ func ExecuteCmd(a *types.App) *cobra.Command {
 				return util.LogErr(types.ErrParseApp, err)
 			}
 
-			var channels types.Channels
-			channels.AnchringTx = make(chan types.Anchoring)
-			channels.HttpClientStartSignal = make(chan bool)
+			err = loadMemoIndex(home)
+			if err != nil {
+				return util.LogErr(types.ErrParseConfig, err)
+			}
+
+			anchorConf := app.AppFile().Get().Config.Anchor
+			channels := types.NewChannels(anchorConf.BatchQueueSize, anchorConf.MaxInFlightTx)
+
+			// The gateway loads keys of the accounts which send anchoring transactions by itself,
+			// so the owner key is not needed to be decrypted in the gateway process.
+			isExecute := cmd.Name() != startCmdName && cmd.Name() != backfillCmdName
 
-			pubClient, privClient, err := initXplaClient(home, true)
+			pubClient, privClient, err := initXplaClient(home, isExecute)
 			if err != nil {
 				return err
 			}
@@ -45,6 +54,9 @@ func ExecuteCmd(a *types.App) *cobra.Command {
 	cmd.AddCommand(
 		EContractCmd(a),
 		StartCmd(a),
+		BackfillCmd(a),
+		grant(a),
+		revoke(a),
 	)
 	return cmd
 }
@@ -9,4 +9,26 @@ const (
 	flagContractAddr     = "address"
 	flagPrivBlockApi     = "priv-block-api"
 	flagLog              = "log"
+	flagKeyName          = "name"
+	flagSubmitterAdd     = "add"
+	flagSubmitterRemove  = "remove"
+	flagGrantee          = "grantee"
+	flagFeeGrant         = "fee-grant"
+	flagFeeOnly          = "fee-only"
+	flagSpendLimit       = "spend-limit"
+	flagExpiration       = "expiration"
+	flagAcceptGeneric    = "accept-generic"
+	flagDryRun           = "dry-run"
+	flagFromHeight       = "from"
+	flagToHeight         = "to"
+	flagOut              = "out"
+	flagRepair           = "repair"
+	flagConcurrency      = "concurrency"
+	flagRate             = "rate"
+	flagSample           = "sample"
+	flagPersistState     = "persist-state"
+	flagOutput           = "output"
+	flagLcd              = "lcd"
+	flagChainId          = "chain-id"
+	flagHeight           = "height"
 )
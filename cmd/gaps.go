@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/spf13/cobra"
+)
+
+// Find heights which are not recorded in the anchor contract.
+// If the repair flag is set, missing heights are anchored by the accounts of the gateway.
+func gaps(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gaps",
+		Short: "find heights which are not recorded in the contract",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s query gaps
+$ %s q gaps --from [start_height] --to [end_height]
+$ %s q gaps --from [start_height] --to [end_height] --concurrency [number]
+$ %s q gaps --from [start_height] --to [end_height] --repair
+		`, defaultAppName, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString(flagContractAddr)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			if addr == "" {
+				addr = app.AppFile().Get().Contract.Address
+			}
+
+			fromHeight, toHeight, err := heightRangeFlags(cmd)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			if fromHeight == "" {
+				fromHeight = types.GenesisBlockNum
+			}
+
+			// Check until the recorded latest block height by default.
+			if toHeight == "" {
+				toHeight, err = gw.QueryRecordedLatestHeight(a.PubClient, addr)
+				if err != nil {
+					return util.LogErr(types.ErrContract, err)
+				}
+
+				if toHeight == "0" {
+					err = printOutput(a, types.NewGapsOutput(fromHeight, toHeight, 0, nil), func() {
+						util.LogInfo("no blocks are recorded in the contract")
+					})
+					if err != nil {
+						return util.LogErr(types.ErrQuery, err)
+					}
+					return nil
+				}
+			}
+
+			concurrency, err := cmd.Flags().GetInt(flagConcurrency)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			isRepair, err := cmd.Flags().GetBool(flagRepair)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			missing, err := gw.FindGaps(a, addr, util.FromStringToUint64(fromHeight), util.FromStringToUint64(toHeight), concurrency)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			missingRanges := gw.GapRanges(missing)
+			err = printOutput(a, types.NewGapsOutput(fromHeight, toHeight, len(missing), missingRanges), func() {
+				if len(missing) == 0 {
+					util.LogInfo(util.G("no gaps"), util.BB("heights=")+fromHeight+"~"+toHeight)
+					return
+				}
+				util.LogWarning(util.R("missing heights="+strings.Join(missingRanges, ",")), util.BB("count=")+util.ToString(len(missing), "0"))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			if len(missing) == 0 || !isRepair {
+				return nil
+			}
+
+			blockApi, err := cmd.Flags().GetString(flagPrivBlockApi)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			home, err := cmd.Flags().GetString(flagHome)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			senders, err := loadSenders(a, home)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+			a.Senders = senders
+
+			anchorConf := app.AppFile().Get().Config.Anchor
+			a.Channels = types.NewChannels(anchorConf.BatchQueueSize, anchorConf.MaxInFlightTx)
+
+			err = gw.StartRepairGaps(a, addr, blockApi, missing)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
+	cmd.Flags().String(flagFromHeight, "", "start block height to check (default: 1)")
+	cmd.Flags().String(flagToHeight, "", "end block height to check (default: recorded latest height)")
+	cmd.Flags().Int(flagConcurrency, gw.DefaultGapProbeConcurrency, "number of parallel queries to the contract")
+	cmd.Flags().Bool(flagRepair, false, "anchor missing heights by the accounts of the gateway")
+
+	return cmd
+}
@@ -1,23 +1,29 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/Moonyongjung/xpla-anchor/app"
 	"github.com/Moonyongjung/xpla-anchor/gw"
 	"github.com/Moonyongjung/xpla-anchor/gw/db"
+	"github.com/Moonyongjung/xpla-anchor/state"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
 	"github.com/spf13/cobra"
 )
 
 const (
-	defaultLog = "none"
-	dbLog      = "db"
+	defaultLog   = "none"
+	dbLog        = "db"
+	startCmdName = "start"
 )
 
 // Running the anchor gateway.
@@ -25,7 +31,7 @@ const (
 // and records info to the anchor contract.
 func StartCmd(a *types.App) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "start",
+		Use:     startCmdName,
 		Aliases: []string{"s"},
 		Short:   "start anchor gateway",
 		Args:    withUsage(cobra.NoArgs),
@@ -35,7 +41,9 @@ $ %s s
 $ %s s --address [contract_address]
 $ %s s --priv-block-api [blockinfo_api_of_private_chain]
 $ %s s --log [db|file] 
-		`, defaultAppName, defaultAppName, defaultAppName, defaultAppName, defaultAppName)),
+$ %s s --persist-state
+$ %s s --dry-run --from [height] --to [height] --out [jsonl_file_path]
+		`, defaultAppName, defaultAppName, defaultAppName, defaultAppName, defaultAppName, defaultAppName, defaultAppName)),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			addr, err := cmd.Flags().GetString(flagContractAddr)
 			if err != nil {
@@ -73,23 +81,192 @@ $ %s s --log [db|file]
 				return util.LogErr(types.ErrGw, err)
 			}
 
+			home, err := cmd.Flags().GetString(flagHome)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			senders, err := loadSenders(a, home)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+			a.Senders = senders
+
+			persistState, err := cmd.Flags().GetBool(flagPersistState)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			// The state of the previous run is restored, and the state is written to the file,
+			// so it can be inspected by the query command.
+			if persistState {
+				err = restoreState(statePath(home))
+				if err != nil {
+					return util.LogErr(types.ErrGw, err)
+				}
+
+				err = state.Mng().Persist(statePath(home))
+				if err != nil {
+					return util.LogErr(types.ErrGw, err)
+				}
+			}
+
+			dryRun, err := cmd.Flags().GetBool(flagDryRun)
+			if err != nil {
+				return util.LogErr(types.ErrGw, err)
+			}
+
+			// The dry run stops after the block of the end height is processed.
+			if dryRun {
+				fromHeight, toHeight, err := heightRangeFlags(cmd)
+				if err != nil {
+					return util.LogErr(types.ErrGw, err)
+				}
+
+				out, err := cmd.Flags().GetString(flagOut)
+				if err != nil {
+					return util.LogErr(types.ErrGw, err)
+				}
+
+				err = gw.StartDryRun(a, addr, blockApi, fromHeight, toHeight, out)
+				if err != nil {
+					return util.LogErr(types.ErrGw, err)
+				}
+
+				return nil
+			}
+
+			adminConf := app.AppFile().Get().Config.Anchor.Admin
+			if adminConf.ListenAddr != "" {
+				err = gw.CheckAdmin(adminConf)
+				if err != nil {
+					return util.LogErr(types.ErrGw, err)
+				}
+				go gw.StartAdmin(adminConf)
+			}
+
 			// Thread gateway.
-			go gw.SendAnchoringTx(a, log)
-			go gw.StartGW(a, addr, blockApi, log)
+			// In the high availability mode, the gateway starts after the instance becomes the leader.
+			// The gateway returns the error when it is not able to run anymore.
+			// Components of the gateway stop when the context is canceled.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			gwErr := make(chan error, 1)
+			go func() {
+				if app.AppFile().Get().Config.Anchor.HA.Backend != "" {
+					gwErr <- gw.StartHA(ctx, a, addr, blockApi, log)
+				} else {
+					gwErr <- gw.StartGW(ctx, a, addr, blockApi, log)
+				}
+			}()
+
+			// SIGUSR1 pauses the gateway, and SIGUSR2 resumes it.
+			// SIGHUP reloads app.yaml, and it is also reloaded when the file is changed.
+			control := make(chan os.Signal, 1)
+			signal.Notify(control, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
+			go func() {
+				for sig := range control {
+					switch sig {
+					case syscall.SIGUSR1:
+						gw.PauseMng().Pause("signal")
+					case syscall.SIGUSR2:
+						gw.PauseMng().Resume("signal")
+					case syscall.SIGHUP:
+						gw.ReloadAppFile(a.AppFilePath, "signal")
+					}
+				}
+			}()
+			go gw.WatchAppFile(a.AppFilePath)
 
 			stop := make(chan os.Signal, 1)
 			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-			<-stop
 
-			util.LogInfo("shutting down the gateway...")
-			util.LogInfo("gateway gracefully stopped")
+			select {
+			case <-stop:
+				util.LogInfo("shutting down the gateway...")
 
-			return nil
+				// The lease is released after all components return, so the standby does not start while a transaction is broadcasted.
+				cancel()
+				<-gwErr
+				gw.StopHA()
+
+				// Transactions which are not confirmed yet are written, so the next run confirms them.
+				err := state.Mng().Flush()
+				if err != nil {
+					util.LogWarning("failed to write the state:", err)
+				}
+				util.LogInfo("gateway gracefully stopped")
+
+				return nil
+
+			case err := <-gwErr:
+				gw.StopHA()
+				if flushErr := state.Mng().Flush(); flushErr != nil {
+					util.LogWarning("failed to write the state:", flushErr)
+				}
+				return util.LogErr(types.ErrGw, "gateway stopped:", err)
+			}
 		},
 	}
 	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
 	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
 	cmd.Flags().String(flagLog, defaultLog, "select log type")
+	cmd.Flags().Bool(flagPersistState, false, "write the state of the gateway to the home directory")
+	cmd.Flags().Bool(flagDryRun, false, "build and sign anchoring transactions without broadcasting")
+	cmd.Flags().String(flagFromHeight, "", "start block height of the dry run (default: next of the recorded latest height)")
+	cmd.Flags().String(flagToHeight, "", "end block height of the dry run")
+	cmd.Flags().String(flagOut, "", "JSONL file path to write results of the dry run (default: stdout)")
 
 	return cmd
 }
+
+// Get the range of block heights from flags.
+func heightRangeFlags(cmd *cobra.Command) (string, string, error) {
+	fromHeight, err := cmd.Flags().GetString(flagFromHeight)
+	if err != nil {
+		return "", "", err
+	}
+
+	toHeight, err := cmd.Flags().GetString(flagToHeight)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, height := range []string{fromHeight, toHeight} {
+		if height == "" {
+			continue
+		}
+
+		if _, err := strconv.ParseUint(height, 10, 64); err != nil {
+			return "", "", errors.New("invalid block height " + height)
+		}
+	}
+
+	return fromHeight, toHeight, nil
+}
+
+// Load the accounts which send anchoring transactions.
+// If accounts are not set in the config, the gateway uses only the default key.
+func loadSenders(a *types.App, home string) ([]*client.XplaClient, error) {
+	names := app.AppFile().Get().Config.Anchor.Accounts
+	if len(names) == 0 {
+		names = []string{defaultKeyName}
+	}
+
+	var senders []*client.XplaClient
+	for _, name := range names {
+		privKey, addr, err := extractKeyByName(home, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sender := *a.PubClient
+		sender.WithPrivateKey(privKey)
+		senders = append(senders, &sender)
+
+		util.LogInfo("public chain account to send anchoring tx=" + addr)
+	}
+
+	return senders, nil
+}
@@ -21,6 +21,8 @@ import (
 // The mnemonic words is not saved in the local directory as home dir,
 // and the private key is recorded in home directory by armored type.
 // The key is encrypted by using passphrase when use key commands.
+// The default key is the owner of the anchor contract, and other keys are able to
+// send anchoring transactions as submitters of the contract.
 func KeyCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "key",
@@ -31,6 +33,7 @@ func KeyCmd() *cobra.Command {
 	cmd.AddCommand(
 		recover(),
 		change(),
+		list(),
 	)
 	return cmd
 }
@@ -39,11 +42,12 @@ func KeyCmd() *cobra.Command {
 func recover() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "recover",
-		Short: `recover key by using mnemonic words. the anchor can own only one key`,
+		Short: `recover key by using mnemonic words`,
 		Args:  withUsage(cobra.NoArgs),
 		Example: strings.TrimSpace(fmt.Sprintf(`
 $ %s key recover
-		`, defaultAppName)),
+$ %s key recover --name [key_name]
+		`, defaultAppName, defaultAppName)),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			home, err := cmd.Flags().GetString(flagHome)
 			if err != nil {
@@ -51,7 +55,13 @@ $ %s key recover
 				return err
 			}
 
-			err = genKey(home, true)
+			name, err := cmd.Flags().GetString(flagKeyName)
+			if err != nil {
+				util.LogErr(types.ErrKey, err)
+				return err
+			}
+
+			err = genKey(home, name, true)
 			if err != nil {
 				return err
 			}
@@ -59,19 +69,56 @@ $ %s key recover
 			return nil
 		},
 	}
+	cmd.Flags().String(flagKeyName, defaultKeyName, "name of the key")
 
 	return cmd
 }
 
 // Change the private key.
-// Should implement the change command to switch the key because The anchor uses only the one private key.
+// Should implement the change command to switch the key which has the same name.
 func change() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "change",
-		Short: `change key by using mnemonic words. the anchor can own only one key`,
+		Short: `change key by using mnemonic words`,
 		Args:  withUsage(cobra.NoArgs),
 		Example: strings.TrimSpace(fmt.Sprintf(`
 $ %s key change
+$ %s key change --name [key_name]
+		`, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, err := cmd.Flags().GetString(flagHome)
+			if err != nil {
+				util.LogErr(types.ErrInit, err)
+				return err
+			}
+
+			name, err := cmd.Flags().GetString(flagKeyName)
+			if err != nil {
+				util.LogErr(types.ErrKey, err)
+				return err
+			}
+
+			err = genKey(home, name, false)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagKeyName, defaultKeyName, "name of the key")
+
+	return cmd
+}
+
+// List names of the keys in the home directory.
+func list() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: `list names of the keys`,
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s key list
 		`, defaultAppName)),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			home, err := cmd.Flags().GetString(flagHome)
@@ -80,11 +127,20 @@ $ %s key change
 				return err
 			}
 
-			err = genKey(home, false)
+			keyFileDir := path.Join(home, defaultKeyPath)
+			entries, err := os.ReadDir(keyFileDir)
 			if err != nil {
+				util.LogErr(types.ErrKey, err)
 				return err
 			}
 
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				util.LogInfo(entry.Name())
+			}
+
 			return nil
 		},
 	}
@@ -93,7 +149,7 @@ $ %s key change
 }
 
 // Generate the private key.
-func genKey(home string, isRecover bool) error {
+func genKey(home string, name string, isRecover bool) error {
 	appFileDir := path.Join(home, defaultAppPath)
 	appFilePath := path.Join(appFileDir, defaultAppFilePath)
 	if _, err := os.Stat(appFilePath); os.IsNotExist(err) {
@@ -101,15 +157,31 @@ func genKey(home string, isRecover bool) error {
 		return err
 	}
 
-	// Default key name.
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		err := "invalid key name"
+		util.LogErr(types.ErrKey, err)
+		return errors.New(err)
+	}
+
 	keyFileDir := path.Join(home, defaultKeyPath)
-	keyFilePath := path.Join(keyFileDir, defaultKeyName)
+	keyFilePath := path.Join(keyFileDir, name)
 
 	if isRecover {
-		if err := os.Mkdir(keyFileDir, os.ModePerm); err != nil {
+		if err := os.MkdirAll(keyFileDir, os.ModePerm); err != nil {
 			util.LogErr(types.ErrKey, err)
 			return err
 		}
+
+		if _, err := os.Stat(keyFilePath); err == nil {
+			err := "key already exists, use change command"
+			util.LogErr(types.ErrKey, err)
+			return errors.New(err)
+		}
+	} else {
+		if _, err := os.Stat(keyFilePath); os.IsNotExist(err) {
+			util.LogErr(types.ErrKey, "no key to change, use recover command")
+			return err
+		}
 	}
 
 	util.LogInfo("input BIP39 mnemonic\n")
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"gopkg.in/yaml.v3"
+)
+
+// The error which exits the anchor with the code except the default error code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// The block is not consistent, so the anchor exits with the code which is distinguished from other errors.
+func notConsistentErr(err error) error {
+	return &exitError{code: types.ExitCodeNotConsistent, err: err}
+}
+
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return types.ExitCodeError
+}
+
+// Check the format of the output.
+// Logs are written to stderr in the structured format, so stdout only has results.
+func initOutput(a *types.App) error {
+	switch a.Output {
+	case types.OutputText:
+		return nil
+	case types.OutputJson, types.OutputYaml:
+		util.SetLogOutput(os.Stderr)
+		return nil
+	default:
+		return errors.New("invalid output " + a.Output + ", select one of text, json and yaml")
+	}
+}
+
+// Print the result of the query to stdout by the format of the output.
+// The text format prints by logs as before.
+func printOutput(a *types.App, result interface{}, printText func()) error {
+	switch a.Output {
+	case types.OutputJson:
+		bytes, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(bytes))
+
+	case types.OutputYaml:
+		// The result is converted through JSON, so YAML has the same field names.
+		bytes, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		var converted interface{}
+		err = json.Unmarshal(bytes, &converted)
+		if err != nil {
+			return err
+		}
+
+		bytes, err = yaml.Marshal(converted)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, string(bytes))
+
+	default:
+		printText()
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	"github.com/spf13/cobra"
+)
+
+// Export and verify proof bundles.
+// The proof bundle is the portable evidence that the block of the private chain is anchored.
+func ProofCmd(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proof",
+		Short: "export and verify proof bundles of anchored blocks",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			err := initOutput(a)
+			if err != nil {
+				return util.LogErr(types.ErrParseConfig, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		exportProof(a),
+		txProof(a),
+		verifyProof(a),
+	)
+	return cmd
+}
+
+// Export the proof bundle of the height to the file.
+func exportProof(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [height]",
+		Short: "export the proof bundle of the anchored block",
+		Args:  withUsage(cobra.ExactArgs(1)),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s proof export [height]
+$ %s proof export [height] --out [file_path]
+$ %s proof export [height] --address [contract_address] --priv-block-api [blockinfo_api_of_private_chain]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, blockApi, err := loadProofApp(cmd, a)
+			if err != nil {
+				return err
+			}
+
+			height := args[0]
+
+			out, err := cmd.Flags().GetString(flagOut)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			if out == "" {
+				out = "proof-" + height + ".json"
+			}
+
+			bundle, err := gw.ExportProof(a, addr, blockApi, height)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			return writeProofBundle(bundle, out)
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
+	cmd.Flags().String(flagOut, "", "file path of the proof bundle (default: proof-[height].json)")
+
+	return cmd
+}
+
+// Export the proof bundle of the transaction of the private chain.
+// The bundle proves the transaction is included in the block, and the block is anchored.
+func txProof(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tx [private_tx_hash]",
+		Short: "export the proof bundle of the transaction of the private chain",
+		Args:  withUsage(cobra.ExactArgs(1)),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s proof tx [private_tx_hash]
+$ %s proof tx [private_tx_hash] --out [file_path]
+$ %s proof tx [private_tx_hash] --height [height_of_the_tx]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, blockApi, err := loadProofApp(cmd, a)
+			if err != nil {
+				return err
+			}
+
+			txHash := args[0]
+
+			height, err := cmd.Flags().GetString(flagHeight)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			out, err := cmd.Flags().GetString(flagOut)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			if out == "" {
+				out = "proof-tx-" + txHash + ".json"
+			}
+
+			bundle, err := gw.ExportTxProof(a, addr, blockApi, txHash, height)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			return writeProofBundle(bundle, out)
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
+	cmd.Flags().String(flagHeight, "", "block height of the tx (default: query the tx to the private chain)")
+	cmd.Flags().String(flagOut, "", "file path of the proof bundle (default: proof-tx-[private_tx_hash].json)")
+
+	return cmd
+}
+
+// Verify the proof bundle.
+// The bundle is checked offline by default, and it is checked again against the public chain if the LCD is set.
+// It does not need the config of the anchor, so auditors can run it with the bundle only.
+func verifyProof(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [file]",
+		Short: "verify the proof bundle",
+		Args:  withUsage(cobra.ExactArgs(1)),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s proof verify [file]
+$ %s proof verify [file] --lcd [public_lcd_url]
+$ %s proof verify [file] --lcd [public_lcd_url] --chain-id [public_chain_id]
+		`, defaultAppName, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bytes, err := os.ReadFile(args[0])
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			var bundle types.ProofBundle
+			err = json.Unmarshal(bytes, &bundle)
+			if err != nil {
+				return util.LogErr(types.ErrProof, "invalid proof bundle:", err)
+			}
+
+			lcd, err := cmd.Flags().GetString(flagLcd)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			chainId, err := cmd.Flags().GetString(flagChainId)
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			if chainId == "" {
+				chainId = bundle.PublicChainID
+			}
+
+			var result types.ProofVerifyResult
+			if lcd == "" {
+				result = gw.VerifyProofBundle(bundle)
+			} else {
+				result = gw.VerifyProofBundleOnline(bundle, client.NewXplaClient(chainId), lcd)
+			}
+
+			err = printOutput(a, result, func() {
+				printProofResult(result)
+			})
+			if err != nil {
+				return util.LogErr(types.ErrProof, err)
+			}
+
+			if !result.Valid {
+				return notConsistentErr(util.LogErr(types.ErrProof, "the proof bundle is not valid"))
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().String(flagLcd, "", "LCD URL of the public chain to check the bundle again (default: offline)")
+	cmd.Flags().String(flagChainId, "", "chain ID of the public chain (default: the chain ID of the bundle)")
+
+	return cmd
+}
+
+// Load the config, the memo index and XPLA clients to export proof bundles.
+func loadProofApp(cmd *cobra.Command, a *types.App) (string, string, error) {
+	home, err := cmd.Flags().GetString(flagHome)
+	if err != nil {
+		return "", "", util.LogErr(types.ErrParseConfig, err)
+	}
+
+	appFilePath, err := getAppFile(home)
+	if err != nil {
+		return "", "", util.LogErr(types.ErrParseApp, err)
+	}
+
+	err = loadMemoIndex(home)
+	if err != nil {
+		return "", "", util.LogErr(types.ErrParseConfig, err)
+	}
+
+	pubClient, privClient, err := initXplaClient(home, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	a.PubClient = pubClient
+	a.PrivClient = privClient
+	a.AppFilePath = appFilePath
+
+	addr, err := cmd.Flags().GetString(flagContractAddr)
+	if err != nil {
+		return "", "", util.LogErr(types.ErrProof, err)
+	}
+
+	if addr == "" {
+		addr = app.AppFile().Get().Contract.Address
+	}
+
+	blockApi, err := cmd.Flags().GetString(flagPrivBlockApi)
+	if err != nil {
+		return "", "", util.LogErr(types.ErrProof, err)
+	}
+
+	err = gw.InitBlockAdapter(blockApi)
+	if err != nil {
+		return "", "", util.LogErr(types.ErrProof, err)
+	}
+
+	return addr, blockApi, nil
+}
+
+// Write the proof bundle to the file.
+// The bundle which can not be verified is not exported.
+func writeProofBundle(bundle types.ProofBundle, out string) error {
+	result := gw.VerifyProofBundle(bundle)
+	if !result.Valid {
+		printProofResult(result)
+		return util.LogErr(types.ErrProof, "the proof bundle of height "+bundle.Record.Height+" is not valid")
+	}
+
+	bytes, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return util.LogErr(types.ErrProof, err)
+	}
+
+	err = os.WriteFile(out, bytes, 0644)
+	if err != nil {
+		return util.LogErr(types.ErrProof, err)
+	}
+
+	util.LogInfo(util.BB("export proof successfully"), util.BB("height=")+bundle.Record.Height, util.BB("txhash=")+bundle.TxHash, util.BB("file=")+out)
+	return nil
+}
+
+func printProofResult(result types.ProofVerifyResult) {
+	if result.PrivateTxHash != "" {
+		util.LogInfo(util.BB("proof of the private tx=") + result.PrivateTxHash)
+	}
+	util.LogInfo(util.BB("proof"), util.BB("height=")+result.Height, util.BB("txhash=")+result.TxHash, util.BB("public height=")+result.PublicHeight)
+
+	for _, check := range result.Checks {
+		if check.Passed {
+			util.LogInfo(util.G(check.Name + " " + verified))
+		} else {
+			util.LogWarning(util.R(check.Name+" failed"), util.BB("reason=")+check.Detail)
+		}
+	}
+
+	// The offline verification only proves that the bundle is consistent.
+	if result.Valid && !result.Authenticated {
+		util.LogWarning("the bundle is unauthenticated, check it again against the public chain by --" + flagLcd)
+	}
+}
@@ -1,15 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/Moonyongjung/xpla-anchor/app"
 	"github.com/Moonyongjung/xpla-anchor/gw"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
-	xtypes "github.com/Moonyongjung/xpla.go/types"
-	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 )
 
@@ -26,6 +27,11 @@ func QueryCmd(a *types.App) *cobra.Command {
 		Aliases: []string{"q"},
 		Short:   "query anchor",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			err := initOutput(a)
+			if err != nil {
+				return util.LogErr(types.ErrParseConfig, err)
+			}
+
 			home, err := cmd.Flags().GetString(flagHome)
 			if err != nil {
 				return util.LogErr(types.ErrParseConfig, err)
@@ -36,6 +42,11 @@ func QueryCmd(a *types.App) *cobra.Command {
 				return util.LogErr(types.ErrParseApp, err)
 			}
 
+			err = loadMemoIndex(home)
+			if err != nil {
+				return util.LogErr(types.ErrParseConfig, err)
+			}
+
 			pubClient, privClient, err := initXplaClient(home, false)
 			if err != nil {
 				return err
@@ -53,22 +64,29 @@ func QueryCmd(a *types.App) *cobra.Command {
 		QContractCmd(a),
 		AccountCmd(a),
 		verify(a),
+		gaps(a),
+		queryState(a),
 	)
 	return cmd
 }
 
 // verify by comparing recorded block info in the contract with response of the private chain.
+// Without the height, the range of heights is verified and the summary is printed.
+// The command fails if any block is not consistent, so it can run periodically such as cron.
 func verify(a *types.App) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "verify [height]",
 		Aliases: []string{"v"},
 		Short:   "verify the block consistency of the private chain",
-		Args:    withUsage(cobra.ExactArgs(1)),
+		Args:    withUsage(cobra.MaximumNArgs(1)),
 		Example: strings.TrimSpace(fmt.Sprintf(`
 $ %s query verify [height]
 $ %s q v [height]
 $ %s q v [height] --address [contract_address] --priv-block-api [blockinfo_api_of_private_chain] 
-		`, defaultAppName, defaultAppName, defaultAppName)),
+$ %s q v --from [start_height] --to [end_height]
+$ %s q v --from [start_height] --to [end_height] --concurrency [number] --rate [requests_per_second]
+$ %s q v --from [start_height] --to [end_height] --sample [number]
+		`, defaultAppName, defaultAppName, defaultAppName, defaultAppName, defaultAppName, defaultAppName)),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Set the API which can check the block info.
 			blockApi, err := cmd.Flags().GetString(flagPrivBlockApi)
@@ -76,16 +94,11 @@ $ %s q v [height] --address [contract_address] --priv-block-api [blockinfo_api_o
 				return util.LogErr(types.ErrQuery, err)
 			}
 
-			// Get the block info from the private chain.
-			res, err := gw.DoRequest(a, blockApi, args[0], false)
+			err = gw.InitBlockAdapter(blockApi)
 			if err != nil {
 				return util.LogErr(types.ErrQuery, err)
 			}
 
-			var block types.Block
-			responseData := util.JsonUnmarshalData(&block, res)
-			mapstructure.Decode(responseData, &block)
-
 			addr, err := cmd.Flags().GetString(flagContractAddr)
 			if err != nil {
 				return util.LogErr(types.ErrQuery, err)
@@ -95,62 +108,271 @@ $ %s q v [height] --address [contract_address] --priv-block-api [blockinfo_api_o
 				addr = app.AppFile().Get().Contract.Address
 			}
 
-			height := args[0]
-
-			// Generate the query message.
-			queryMsg := xtypes.QueryMsg{
-				ContractAddress: addr,
-				QueryMsg:        `{"block_data":{"height":"` + height + `"}}`,
+			fromHeight, toHeight, err := heightRangeFlags(cmd)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
 			}
 
-			// Request query by using XPLA client.
-			resContract, err := a.PubClient.QueryContract(queryMsg).Query()
-			if err != nil {
-				return util.LogErr(types.ErrContract, err)
+			if len(args) == 1 {
+				if fromHeight != "" || toHeight != "" {
+					return util.LogErr(types.ErrQuery, "the height and the range of heights can not be used together")
+				}
+				return verifyHeight(a, addr, blockApi, args[0])
 			}
 
-			var blockInfo types.QueryBlockInfoResponse
-			resContractData := util.JsonUnmarshalData(&blockInfo, []byte(resContract))
-			mapstructure.Decode(resContractData, &blockInfo)
+			return verifyRange(cmd, a, addr, blockApi, fromHeight, toHeight)
+		},
+	}
+	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
+	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
+	cmd.Flags().String(flagFromHeight, "", "start block height to verify (default: 1)")
+	cmd.Flags().String(flagToHeight, "", "end block height to verify (default: recorded latest height)")
+	cmd.Flags().Int(flagConcurrency, gw.DefaultVerifyConcurrency, "number of heights which are verified in parallel")
+	cmd.Flags().Float64(flagRate, 0, "max number of heights which are verified per second (default: unlimited)")
+	cmd.Flags().Int(flagSample, 0, "number of heights which are sampled randomly in the range (default: all heights)")
 
-			// Compare.
-			util.LogInfo("[priv chain]", util.BB("height=")+block.Block.Header.Height)
-			util.LogInfo("[contract]  ", util.BB("height=")+blockInfo.Data.Height)
-			if block.Block.Header.Height == blockInfo.Data.Height {
-				util.LogInfo(util.G("height " + verified))
-			} else {
-				util.LogWarning(util.R(notVerified))
-			}
+	return cmd
+}
 
-			util.LogInfo("[priv chain]", util.BB("block hash=")+block.BlockID.Hash)
-			util.LogInfo("[contract]  ", util.BB("block hash=")+blockInfo.Data.BlockHash)
-			if block.BlockID.Hash == blockInfo.Data.BlockHash {
-				util.LogInfo(util.G("hash " + verified))
-			} else {
-				util.LogWarning(util.R(notVerified))
-			}
+// Verify the height, and print fields of the private chain and the recorded block info.
+func verifyHeight(a *types.App, addr, blockApi, height string) error {
+	// Get the block info from the private chain.
+	res, err := gw.DoRequest(a, blockApi, height)
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	header, err := gw.ParseBlock(res)
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	sink := "contract"
+	recordedLabel := "[contract]  "
+	if gw.IsMemoSink() {
+		sink = "memo"
+		recordedLabel = "[memo]      "
+	}
+	report := types.NewVerifyReport(height, sink)
+
+	// Get the recorded block info from the contract, or from the memo index and the memo of the transaction.
+	recorded, recordedErr := gw.QueryRecordedBlockData(a.PubClient, addr, height)
+	if recordedErr != nil {
+		if !errors.Is(recordedErr, gw.ErrNotRecorded) {
+			return util.LogErr(types.ErrContract, recordedErr)
+		}
+
+		report.Missing = true
+		err = printOutput(a, report, func() {
+			util.LogWarning(util.R(notVerified))
+		})
+		if err != nil {
+			return util.LogErr(types.ErrQuery, err)
+		}
+		return notConsistentErr(util.LogErr(types.ErrContract, recordedErr))
+	}
+
+	// Hashes of the contract can be recorded by another encoding, so both are compared by the normalized hex.
+	recorded = gw.NormalizeData(recorded)
 
-			util.LogInfo("[priv chain]", util.BB("merkle root=")+block.Block.Header.DataHash)
-			util.LogInfo("[contract]  ", util.BB("merkle root=")+blockInfo.Data.DataMerkle)
-			if block.Block.Header.DataHash == blockInfo.Data.DataMerkle {
-				util.LogInfo(util.G("merkle " + verified))
+	// The hash of the private chain is checked by the hash which is recomputed from its header.
+	// If it is not matched, fields of the header are checked by the config and the previous block to find which field is wrong.
+	chainID := app.AppFile().Get().Config.PrivateChain.ChainID
+	headerErr := gw.CheckHeaderHash(header)
+	var lastHash string
+	if headerErr != nil {
+		lastHash = gw.LastBlockHash(a, blockApi, header)
+		report.HeaderFields = gw.CheckHeaderFields(header, chainID, lastHash)
+	}
+	mismatches := gw.CompareBlock(header, chainID, lastHash, recorded)
+
+	// Compare.
+	fields := []struct {
+		name     string
+		label    string
+		priv     string
+		recorded string
+	}{
+		{types.VerifyFieldHeight, "height=", header.Height, recorded.Height},
+		{types.VerifyFieldHash, "block hash=", header.Hash, recorded.BlockHash},
+		{types.VerifyFieldMerkle, "merkle root=", header.DataHash, recorded.DataMerkle},
+		{types.VerifyFieldTimestamp, "timestamp=", header.Time, recorded.Timestamp},
+	}
+
+	for _, field := range fields {
+		report.Fields = append(report.Fields, types.VerifyField{
+			Field:    field.name,
+			Private:  field.priv,
+			Recorded: field.recorded,
+			Matched:  field.priv == field.recorded,
+		})
+	}
+	report.Consistent = len(mismatches) == 0
+	report.Mismatches = append(report.Mismatches, mismatches...)
+
+	computedHash, computeErr := gw.ComputeHeaderHash(header)
+	if computeErr == nil {
+		report.ComputedHash = computedHash
+	}
+
+	err = printOutput(a, report, func() {
+		for _, field := range fields {
+			util.LogInfo("[priv chain]", util.BB(field.label)+field.priv)
+			util.LogInfo(recordedLabel, util.BB(field.label)+field.recorded)
+			if field.priv == field.recorded {
+				util.LogInfo(util.G(field.name + " " + verified))
 			} else {
 				util.LogWarning(util.R(notVerified))
 			}
+		}
 
-			util.LogInfo("[priv chain]", util.BB("timestamp=")+block.Block.Header.Time)
-			util.LogInfo("[contract]  ", util.BB("timestamp=")+blockInfo.Data.Timestamp)
-			if block.Block.Header.Time == blockInfo.Data.Timestamp {
-				util.LogInfo(util.G("timestamp " + verified))
-			} else {
-				util.LogWarning(util.R(notVerified))
+		if report.ComputedHash != "" {
+			util.LogInfo("[computed]  ", util.BB("block hash=")+report.ComputedHash)
+		}
+		// The block which is not the tendermint header is not recomputed.
+		switch {
+		case headerErr != nil:
+			util.LogWarning(util.R(notVerified), util.BB("reason=")+headerErr.Error())
+			for _, field := range report.HeaderFields {
+				util.LogInfo("[priv chain]", util.BB(field.Field+"=")+field.Private)
+				util.LogInfo("[expected]  ", util.BB(field.Field+"=")+field.Expected)
+				if field.Matched {
+					util.LogInfo(util.G(field.Field + " " + verified))
+				} else {
+					util.LogWarning(util.R(notVerified))
+				}
+			}
+			if header.VersionApp == "" {
+				util.LogWarning("the app version is omitted by the block API, it is hashed as 0")
 			}
+			if headerMismatches := gw.HeaderMismatches(header, chainID, lastHash); len(headerMismatches) != 0 {
+				util.LogWarning(util.R(notVerified), util.BB("header fields=")+strings.Join(headerMismatches, ","))
+			}
+		case report.ComputedHash != "":
+			util.LogInfo(util.G(types.VerifyFieldHeader + " " + verified))
+		}
+	})
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	if len(mismatches) != 0 {
+		return notConsistentErr(util.LogErr(types.ErrQuery, "block is not consistent, height="+height, "mismatched fields="+strings.Join(mismatches, ",")))
+	}
+
+	return nil
+}
 
+// Verify the range of heights, and print the summary.
+func verifyRange(cmd *cobra.Command, a *types.App, addr, blockApi, fromHeight, toHeight string) error {
+	if fromHeight == "" {
+		fromHeight = types.GenesisBlockNum
+	}
+
+	// Verify until the recorded latest block height by default.
+	if toHeight == "" {
+		var err error
+		toHeight, err = gw.QueryRecordedLatestHeight(a.PubClient, addr)
+		if err != nil {
+			return util.LogErr(types.ErrContract, err)
+		}
+
+		if toHeight == "0" {
+			err = printOutput(a, types.NewVerifySummary(fromHeight, toHeight, false), func() {
+				util.LogInfo("no blocks are recorded in the contract")
+			})
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
 			return nil
-		},
+		}
 	}
-	cmd.Flags().String(flagContractAddr, "", "address of the anchor contract")
-	cmd.Flags().String(flagPrivBlockApi, defaultPrivBlockApi, "block query API of the private chain(except height)")
 
-	return cmd
+	from := util.FromStringToUint64(fromHeight)
+	to := util.FromStringToUint64(toHeight)
+	if from == 0 || to < from {
+		return util.LogErr(types.ErrQuery, "end height must be bigger than start height, and start height must be bigger than 0")
+	}
+
+	concurrency, err := cmd.Flags().GetInt(flagConcurrency)
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	rate, err := cmd.Flags().GetFloat64(flagRate)
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	sample, err := cmd.Flags().GetInt(flagSample)
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	heights := gw.VerifyTargetHeights(from, to, sample)
+	sampled := uint64(len(heights)) < to-from+1
+
+	util.LogInfo(util.BB("verify heights=")+fromHeight+"~"+toHeight, util.BB("count=")+util.ToString(len(heights), "0"))
+
+	summary := types.NewVerifySummary(fromHeight, toHeight, sampled)
+	for _, result := range gw.VerifyHeights(context.Background(), a, addr, blockApi, heights, concurrency, rate) {
+		summary.Add(result)
+	}
+
+	err = printOutput(a, summary, func() {
+		printVerifySummary(summary)
+	})
+	if err != nil {
+		return util.LogErr(types.ErrQuery, err)
+	}
+
+	// Heights which are failed to be checked are not able to be judged, so they exit by the default error code.
+	if summary.Mismatched != 0 || summary.Missing != 0 {
+		return notConsistentErr(util.LogErr(types.ErrQuery, "blocks are not consistent"))
+	}
+	if summary.Failed != 0 {
+		return util.LogErr(types.ErrQuery, "failed to verify heights="+heightRanges(summary.FailedHeights))
+	}
+
+	return nil
+}
+
+func printVerifySummary(summary types.VerifySummary) {
+	util.LogInfo(util.BB("verify summary"), util.BB("heights=")+summary.FromHeight+"~"+summary.ToHeight, util.BB("sampled=")+strconv.FormatBool(summary.Sampled))
+	util.LogInfo(util.BB("checked=") + util.ToString(summary.Checked, "0"))
+	util.LogInfo(util.BB("matched=") + util.ToString(summary.Matched, "0"))
+
+	var fields []string
+	for _, field := range types.VerifyFields {
+		fields = append(fields, field+"="+util.ToString(summary.MismatchedFields[field], "0"))
+	}
+	mismatched := util.BB("mismatched=") + util.ToString(summary.Mismatched, "0")
+	if summary.Mismatched != 0 {
+		util.LogWarning(util.R(mismatched), util.BB("fields=")+strings.Join(fields, ","), util.BB("heights=")+heightRanges(summary.MismatchedHeights))
+	} else {
+		util.LogInfo(mismatched)
+	}
+
+	missing := util.BB("missing=") + util.ToString(summary.Missing, "0")
+	if summary.Missing != 0 {
+		util.LogWarning(util.R(missing), util.BB("heights=")+heightRanges(summary.MissingHeights))
+	} else {
+		util.LogInfo(missing)
+	}
+
+	failed := util.BB("failed=") + util.ToString(summary.Failed, "0")
+	if summary.Failed != 0 {
+		util.LogWarning(util.R(failed), util.BB("heights=")+heightRanges(summary.FailedHeights))
+	} else {
+		util.LogInfo(failed)
+	}
+}
+
+// Heights are printed by ranges such as 1~10, because the range of heights can be large.
+func heightRanges(heights []string) string {
+	var numbers []uint64
+	for _, height := range heights {
+		numbers = append(numbers, util.FromStringToUint64(height))
+	}
+	return strings.Join(gw.GapRanges(numbers), ",")
 }
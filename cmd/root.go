@@ -9,6 +9,7 @@ import (
 	"syscall"
 
 	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
 	"github.com/Moonyongjung/xpla.go/client"
@@ -22,14 +23,15 @@ import (
 
 var (
 	// default values of the anchor
-	defaultAppName      = "anc"
-	defaultHome         = filepath.Join(os.Getenv("HOME"), ".anchor")
-	defaultAppPath      = "config"
-	defaultKeyPath      = "keys"
-	defaultAppFilePath  = "app.yaml"
-	defaultConfigPath   = "./config.yaml"
-	defaultKeyName      = "AnchorKey"
-	defaultPrivBlockApi = "/blocks"
+	defaultAppName       = "anc"
+	defaultHome          = filepath.Join(os.Getenv("HOME"), ".anchor")
+	defaultAppPath       = "config"
+	defaultKeyPath       = "keys"
+	defaultAppFilePath   = "app.yaml"
+	defaultConfigPath    = "./config.yaml"
+	defaultKeyName       = "AnchorKey"
+	defaultPrivBlockApi  = "/blocks"
+	defaultMemoIndexPath = "index/memo.jsonl"
 )
 
 // Start the command for the anchor.
@@ -44,7 +46,7 @@ func Start() {
 	defer cancel()
 
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -74,6 +76,8 @@ func NewRootCmd() *cobra.Command {
 		panic(err)
 	}
 
+	rootCmd.PersistentFlags().StringVar(&a.Output, flagOutput, types.OutputText, "output format of query results (text|json|yaml)")
+
 	// Register subcommands
 	rootCmd.AddCommand(
 		InitCmd(),
@@ -81,6 +85,8 @@ func NewRootCmd() *cobra.Command {
 		ConfigCmd(),
 		ExecuteCmd(a),
 		QueryCmd(a),
+		AuditCmd(a),
+		ProofCmd(a),
 	)
 
 	return rootCmd
@@ -157,15 +163,45 @@ func initXplaClient(home string, isExecute bool) (*client.XplaClient, *client.Xp
 	}
 
 	priXplac := client.NewXplaClient(privChainId).WithURL(privLcd)
+
+	// The XPLA client requests by the default transport of the net/http.
+	err := gw.InitChainTransports(conf)
+	if err != nil {
+		return nil, nil, util.LogErr(types.ErrGenXplaClient, err)
+	}
 	util.LogInfo("generate XPLA client successfully")
 
 	return pubXplac, priXplac, nil
 }
 
-// Extract the private key
+// Load the local index of the memo sink.
+func loadMemoIndex(home string) error {
+	err := gw.CheckSink()
+	if err != nil {
+		return err
+	}
+
+	if !gw.IsMemoSink() {
+		return nil
+	}
+
+	indexFile := app.AppFile().Get().Config.Anchor.Memo.IndexFile
+	if indexFile == "" {
+		indexFile = path.Join(home, defaultMemoIndexPath)
+	}
+
+	return gw.MemoIndexMng().Load(indexFile)
+}
+
+// Extract the default private key
 func extractKey(home string) (cryptotypes.PrivKey, string, error) {
+	return extractKeyByName(home, defaultKeyName)
+}
+
+// Extract the private key which is saved by the key name
+func extractKeyByName(home string, name string) (cryptotypes.PrivKey, string, error) {
 	keyFileDir := path.Join(home, defaultKeyPath)
-	keyFilePath := path.Join(keyFileDir, defaultKeyName)
+	keyFilePath := path.Join(keyFileDir, name)
 	if _, err := os.Stat(keyFilePath); os.IsNotExist(err) {
 		return nil, "", util.LogErr(types.ErrGenXplaClient, "run recovering key before execute")
 	}
@@ -176,7 +212,7 @@ func extractKey(home string) (cryptotypes.PrivKey, string, error) {
 	}
 
 	xutil.MakeEncodingConfig()
-	util.LogInfo("input passphrase")
+	util.LogInfo("input passphrase of " + name)
 	passphraseByte, err := term.ReadPassword(int(syscall.Stdin))
 	if err != nil {
 		return nil, "", util.LogErr(types.ErrGenXplaClient, err)
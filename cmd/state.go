@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Moonyongjung/xpla-anchor/gw"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/spf13/cobra"
+)
+
+const (
+	stateDir  = "state"
+	stateFile = "gateway.json"
+)
+
+// Get the file path of the gateway state.
+func statePath(home string) string {
+	return path.Join(home, stateDir, stateFile)
+}
+
+// Query the state of the gateway.
+// The state is written by the gateway which runs with the persist state flag.
+func queryState(a *types.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "query the state of the gateway",
+		Args:  withUsage(cobra.NoArgs),
+		Example: strings.TrimSpace(fmt.Sprintf(`
+$ %s query state
+$ %s q state
+		`, defaultAppName, defaultAppName)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, err := cmd.Flags().GetString(flagHome)
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			snapshot, err := state.Load(statePath(home))
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			bytes, err := json.MarshalIndent(snapshot, "", "  ")
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+
+			err = printOutput(a, snapshot, func() {
+				util.LogInfo(string(bytes))
+			})
+			if err != nil {
+				return util.LogErr(types.ErrQuery, err)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// Restore the state which is persisted by the previous run of the gateway.
+// The gateway which is paused manually is paused again.
+func restoreState(path string) error {
+	snapshot, ok, err := state.Mng().Restore(path)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	util.LogInfo(util.BB("restore the state"), util.BB("file=")+path, util.BB("updated at=")+snapshot.UpdatedAt)
+	if snapshot.ManualPause {
+		gw.PauseMng().Pause("the restored state")
+	}
+
+	return nil
+}
@@ -4,8 +4,8 @@ use cosmwasm_std::{DepsMut, Env, MessageInfo, Response, StdError};
 use cw2::{set_contract_version, get_contract_version};
 
 use crate::error::{ContractError};
-use crate::handler::{check_owner};
-use crate::msg::{ExecuteMsg, InstantiateMsg, MigrateMsg, AnchoringMsg};
+use crate::handler::{check_owner, check_submitter};
+use crate::msg::{ExecuteMsg, InstantiateMsg, MigrateMsg, AnchoringMsg, UpdateSubmittersMsg};
 use crate::state::{Config, CONFIG, ANCHORING, BlockData, LATEST};
 
 // version info for migration info
@@ -21,6 +21,7 @@ pub fn instantiate(
 ) -> Result<Response, ContractError> {
     let config = Config {
         owner: info.sender,
+        submitters: vec![],
     };
 
     set_contract_version(deps.storage, CONTRACT_NAME, CONTRACT_VERSION)?;
@@ -45,6 +46,7 @@ pub fn execute(
     
     match msg {
         ExecuteMsg::Anchoring(msg) => anchoring(deps, info, env, config, msg),
+        ExecuteMsg::UpdateSubmitters(msg) => update_submitters(deps, info, env, config, msg),
     }
 }
 
@@ -56,7 +58,7 @@ pub fn anchoring(
     config: Config,
     msg: AnchoringMsg,
 ) -> Result<Response, ContractError> {
-    check_owner(&info, &config)?;
+    check_submitter(&info, &config)?;
 
     let anchoring = msg.data;
     
@@ -72,13 +74,55 @@ pub fn anchoring(
         })
         .collect();
     
-    LATEST.save(deps.storage, &msg.latest)?;
+    // anchoring messages from several submitters may be included out of order,
+    // so the latest height only moves forward.
+    let latest = LATEST.load(deps.storage)?;
+    if parse_height(&msg.latest)? > parse_height(&latest)? {
+        LATEST.save(deps.storage, &msg.latest)?;
+    }
 
     Ok(Response::new()
         .add_attribute("method", "anchoring")
+        .add_attribute("sender", info.sender)
+    )
+}
+
+// add or remove the accounts which are able to send anchoring messages
+pub fn update_submitters(
+    deps: DepsMut,
+    info: MessageInfo,
+    _env: Env,
+    mut config: Config,
+    msg: UpdateSubmittersMsg,
+) -> Result<Response, ContractError> {
+    check_owner(&info, &config)?;
+
+    for addr in msg.add.iter() {
+        let addr = deps.api.addr_validate(addr)?;
+        if !config.submitters.contains(&addr) {
+            config.submitters.push(addr);
+        }
+    }
+
+    for addr in msg.remove.iter() {
+        let addr = deps.api.addr_validate(addr)?;
+        config.submitters.retain(|x| x != &addr);
+    }
+
+    CONFIG.save(deps.storage, &config)?;
+
+    Ok(Response::new()
+        .add_attribute("method", "update_submitters")
+        .add_attribute("submitters", config.submitters.len().to_string())
     )
 }
 
+fn parse_height(height: &str) -> Result<u64, ContractError> {
+    height
+        .parse::<u64>()
+        .map_err(|_| StdError::generic_err("invalid block height").into())
+}
+
 #[cfg_attr(not(feature = "library"), entry_point)]
 pub fn migrate(
     deps: DepsMut, 
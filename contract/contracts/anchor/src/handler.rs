@@ -9,4 +9,13 @@ pub fn check_owner(info: &MessageInfo, config: &Config) -> Result<String, Contra
     }
 
     Err(ContractError::Unauthorized {})
-}
\ No newline at end of file
+}
+
+/// check the owner or the authorized submitters of contract
+pub fn check_submitter(info: &MessageInfo, config: &Config) -> Result<String, ContractError> {
+    if info.sender == config.owner || config.submitters.contains(&info.sender) {
+        return Ok(info.sender.to_string());
+    }
+
+    Err(ContractError::Unauthorized {})
+}
@@ -7,6 +7,7 @@ pub struct InstantiateMsg {
 #[cw_serde]
 pub enum ExecuteMsg {
     Anchoring(AnchoringMsg),
+    UpdateSubmitters(UpdateSubmittersMsg),
 }
 
 #[cw_serde]
@@ -19,6 +20,9 @@ pub enum QueryMsg {
 
     #[returns(LatestBlockResponse)]
     LatestBlock {},
+
+    #[returns(ConfigResponse)]
+    Config {},
 }
 
 // msgs
@@ -28,6 +32,12 @@ pub struct AnchoringMsg {
     pub latest: String,
 }
 
+#[cw_serde]
+pub struct UpdateSubmittersMsg {
+    pub add: Vec<String>,
+    pub remove: Vec<String>,
+}
+
 #[cw_serde]
 pub struct Data {
     pub height: String,
@@ -50,6 +60,12 @@ pub struct LatestBlockResponse {
     pub latest_height: String,
 }
 
+#[cw_serde]
+pub struct ConfigResponse {
+    pub owner: String,
+    pub submitters: Vec<String>,
+}
+
 #[cw_serde]
 pub struct MigrateMsg {}
 
@@ -1,8 +1,8 @@
 #[cfg(not(feature = "library"))]
 use cosmwasm_std::entry_point;
 use cosmwasm_std::{to_binary, Binary, Env, StdResult, Deps, StdError};
-use crate::msg::{QueryMsg, BlockDataResponse, LatestBlockResponse};
-use crate::state::{ANCHORING, LATEST};
+use crate::msg::{QueryMsg, BlockDataResponse, LatestBlockResponse, ConfigResponse};
+use crate::state::{ANCHORING, LATEST, CONFIG};
 
 #[cfg_attr(not(feature = "library"), entry_point)]
 pub fn query(
@@ -13,6 +13,7 @@ pub fn query(
     match msg {
         QueryMsg::BlockData { height } => to_binary(&block_data(deps, height)?),
         QueryMsg::LatestBlock {} => to_binary(&latest_block(deps)?),
+        QueryMsg::Config {} => to_binary(&config(deps)?),
     }
 }
 
@@ -40,4 +41,13 @@ fn latest_block(deps: Deps) -> StdResult<LatestBlockResponse> {
         latest_height
     })
 
-}
\ No newline at end of file
+}
+
+// query the owner and the authorized submitters
+fn config(deps: Deps) -> StdResult<ConfigResponse> {
+    let config = CONFIG.load(deps.storage)?;
+    Ok(ConfigResponse {
+        owner: config.owner.to_string(),
+        submitters: config.submitters.iter().map(|x| x.to_string()).collect(),
+    })
+}
@@ -6,6 +6,9 @@ use cw_storage_plus::{Item, Map};
 pub struct Config {
     // the contract owner.
     pub owner: Addr,
+    // the accounts which are able to send anchoring messages except the owner.
+    #[serde(default)]
+    pub submitters: Vec<Addr>,
 }
 
 impl Config {
@@ -0,0 +1,151 @@
+package gw
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultAccountReportPeriod = 600000
+	feeDenom                   = "axpla"
+)
+
+var accountStatInstance *AccountStat
+var accountStatOnce sync.Once
+
+// Record the usage of the accounts which send anchoring transactions.
+type AccountStat struct {
+	mu      sync.Mutex
+	TxCount map[string]uint64
+	FeeUsed map[string]*big.Int
+}
+
+func AccountStatMng() *AccountStat {
+	accountStatOnce.Do(func() {
+		accountStatInstance = &AccountStat{
+			TxCount: make(map[string]uint64),
+			FeeUsed: make(map[string]*big.Int),
+		}
+	})
+	return accountStatInstance
+}
+
+// Add the fee of the confirmed anchoring transaction.
+func (s *AccountStat) AddTx(addr string, txRes types.QueryTxResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.TxCount[addr]++
+
+	if s.FeeUsed[addr] == nil {
+		s.FeeUsed[addr] = big.NewInt(0)
+	}
+
+	for _, fee := range txRes.Tx.AuthInfo.Fee.Amount {
+		if fee.Denom != feeDenom {
+			continue
+		}
+
+		amount, ok := new(big.Int).SetString(fee.Amount, 10)
+		if ok {
+			s.FeeUsed[addr].Add(s.FeeUsed[addr], amount)
+		}
+	}
+}
+
+func (s *AccountStat) Now(addr string) (uint64, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feeUsed := "0"
+	if s.FeeUsed[addr] != nil {
+		feeUsed = s.FeeUsed[addr].String()
+	}
+
+	return s.TxCount[addr], feeUsed
+}
+
+// Report the balance and the fee usage of each account periodically.
+// The state of the rate limiter for the private chain is reported together.
+func reportAccounts(ctx context.Context, a *types.App) {
+	xplac := cloneClient(a.PubClient)
+
+	for {
+		period := app.AppFile().Get().Config.Anchor.AccountReportPeriod
+		if period <= 0 {
+			period = defaultAccountReportPeriod
+		}
+
+		if !sleepContext(ctx, time.Millisecond*time.Duration(period)) {
+			return
+		}
+
+		for _, sender := range a.Senders {
+			addr, err := senderAddress(sender)
+			if err != nil {
+				util.LogWarning(err)
+				continue
+			}
+
+			balance, err := queryBalance(xplac, addr)
+			if err != nil {
+				util.LogWarning("failed to query balance of", addr, err)
+				continue
+			}
+
+			txCount, feeUsed := AccountStatMng().Now(addr)
+			util.LogInfo(
+				util.BB("account=")+addr,
+				util.BB("balance=")+balance+feeDenom,
+				util.BB("txs=")+util.FromUint64ToString(txCount),
+				util.BB("fee used=")+feeUsed+feeDenom,
+			)
+		}
+
+		if paused, reason, since := PauseMng().Status(); paused == types.GatewayStatusPaused {
+			util.LogInfo(util.BB("gateway is paused"), util.BB("reason=")+reason, util.BB("since=")+since)
+		}
+
+		status := RateLimitMng().Status()
+		util.LogInfo(
+			util.BB("rate limit state=")+status.State,
+			util.BB("rate=")+util.ToString(status.Rate, "0"),
+			util.BB("burst=")+util.ToString(status.Burst, "0"),
+			util.BB("throttled=")+util.FromUint64ToString(status.Throttled),
+		)
+	}
+}
+
+// Query the balance of the fee denom.
+func queryBalance(xplac *client.XplaClient, addr string) (string, error) {
+	var res string
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		res, err = xplac.BankBalances(xtypes.BankBalancesMsg{Address: addr}).Query()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var balances types.QueryBalancesResponse
+	responseData := util.JsonUnmarshalData(&balances, []byte(res))
+	mapstructure.Decode(responseData, &balances)
+
+	for _, balance := range balances.Balances {
+		if balance.Denom == feeDenom {
+			return balance.Amount, nil
+		}
+	}
+
+	return "0", nil
+}
@@ -0,0 +1,101 @@
+package gw
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const adminReadTimeout = 10
+
+// Check the admin endpoint is safe to serve.
+// The endpoint without the token is only served on the loopback address, because anyone who reaches it can pause the gateway.
+func CheckAdmin(adminConf app.Admin) error {
+	if adminConf.Token != "" {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(adminConf.ListenAddr)
+	if err != nil {
+		return errors.New("invalid listen address of the admin endpoint: " + err.Error())
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsLoopback() {
+		return errors.New("the admin endpoint without the token is only served on the loopback address, set Admin.Token to listen on " + adminConf.ListenAddr)
+	}
+
+	return nil
+}
+
+// Serve the admin HTTP endpoint.
+// GET /status responds the status of the gateway, and POST /pause and POST /resume control the gateway.
+func StartAdmin(adminConf app.Admin) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", adminHandler(adminConf, http.MethodGet, func() {}))
+	mux.HandleFunc("/pause", adminHandler(adminConf, http.MethodPost, func() { PauseMng().Pause("admin endpoint") }))
+	mux.HandleFunc("/resume", adminHandler(adminConf, http.MethodPost, func() { PauseMng().Resume("admin endpoint") }))
+
+	server := &http.Server{
+		Addr:        adminConf.ListenAddr,
+		Handler:     mux,
+		ReadTimeout: time.Second * adminReadTimeout,
+	}
+
+	util.LogInfo(util.BB("admin endpoint=") + adminConf.ListenAddr)
+
+	err := server.ListenAndServe()
+	if err != nil {
+		util.LogWarning("admin endpoint is stopped:", err)
+	}
+}
+
+// Check the method and the token, run the action and respond the status.
+func adminHandler(adminConf app.Admin, method string, action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if adminConf.Token != "" && !validAdminToken(r, adminConf.Token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		action()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GatewayStatus())
+	}
+}
+
+// Compare the bearer token in the constant time, so the token is not guessed by the response time.
+func validAdminToken(r *http.Request, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
+
+// Get the status of the gateway.
+func GatewayStatus() types.GatewayStatus {
+	var status types.GatewayStatus
+
+	status.Status, status.PauseReason, status.PausedSince = PauseMng().Status()
+	status.PublicEndpoint = PubEndpointMng().Current()
+	status.PrivateEndpoints = PrivEndpointMng().Status()
+	status.RateLimit = RateLimitMng().Status()
+	status.State = state.Mng().Snapshot()
+
+	return status
+}
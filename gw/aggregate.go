@@ -1,13 +1,14 @@
 package gw
 
 import (
+	"context"
 	"strings"
 	"time"
 
 	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
-	"github.com/mitchellh/mapstructure"
 )
 
 const (
@@ -15,59 +16,109 @@ const (
 	waitingBlockTime       = 6000
 )
 
-var dataAggregate []types.Data
+// Blocks which are collected by the fetcher, but not sent as the batch yet.
+// The fetcher owns its aggregator, so blocks of the stopped fetcher are never mixed with the batch of the next one.
+type aggregator struct {
+	data []types.Data
+}
 
 // Aggregate info of blocks.
 // Handle parameters which is some of the cosmos based block data such as height, hash and etc.
-func aggregate(a *types.App, responseBody []byte) {
+// Return true if the block is collected, so the gateway can request the next block.
+func (ag *aggregator) aggregate(ctx context.Context, a *types.App, responseBody []byte) bool {
 	if strings.Contains(string(responseBody), requestBiggerHeightErr) {
 		util.LogWait("wating for creating new block...")
-		BlockListMng().DecreaseLatestBlockHeight()
-		time.Sleep(time.Millisecond * time.Duration(waitingBlockTime))
+		sleepContext(ctx, time.Millisecond*time.Duration(waitingBlockTime))
+
+		return false
+	}
+
+	count := app.AppFile().Get().Config.Anchor.CollectBlockCount
+
+	header, err := ParseBlock(responseBody)
+	if err != nil {
+		util.LogWarning("invalid block response, check the LCD URL or block info API:", err)
+		sleepContext(ctx, time.Millisecond*time.Duration(waitingBlockTime))
+
+		return false
+	}
+
+	// The block is requested again, so the block of the wrong hash is never anchored.
+	err = CheckHeaderHash(header)
+	if err != nil {
+		util.LogWarning("refuse the block:", err)
+		sleepContext(ctx, time.Millisecond*time.Duration(waitingBlockTime))
+
+		return false
+	}
 
-		a.Channels.HttpClientStartSignal <- true
+	util.LogInfo(util.BB("height=")+header.Height, util.BB("hash=")+header.Hash)
 
-	} else {
-		count := app.AppFile().Get().Config.Anchor.CollectBlockCount
+	newData := types.NewData(header.Height, header.Hash, header.DataHash, header.Time)
 
-		var block types.Block
-		responseData := util.JsonUnmarshalData(&block, responseBody)
-		mapstructure.Decode(responseData, &block)
+	// Listing aggreated info.
+	ag.data = append(ag.data, newData)
 
-		util.LogInfo(util.BB("height=")+block.Block.Header.Height, util.BB("hash=")+block.BlockID.Hash)
+	// The count can be reloaded to the value which is smaller than collected blocks.
+	if len(ag.data) >= count {
+		return ag.send(ctx, a)
+	}
+
+	return true
+}
 
-		if block.BlockID.Hash == "" || block.Block.Header.Height == "" {
-			util.LogWarning("empty response, check the LCD URL or block info API")
-			BlockListMng().DecreaseLatestBlockHeight()
-			time.Sleep(time.Millisecond * time.Duration(waitingBlockTime))
+// Send the aggregated blocks which are less than the collect count.
+// It is used when the gateway stops at the end of the requested range.
+func (ag *aggregator) flush(ctx context.Context, a *types.App) {
+	if len(ag.data) != 0 {
+		ag.send(ctx, a)
+	}
+}
 
-		} else {
-			height := block.Block.Header.Height
-			block_hash := block.BlockID.Hash
-			data_merkle := block.Block.Header.DataHash
-			timestamp := block.Block.Header.Time
+// Drop blocks which are not sent, and move the next height back to the first of them.
+// It is called when the fetcher returns, so the restarted fetcher requests them again.
+func (ag *aggregator) rewind() {
+	if len(ag.data) == 0 {
+		return
+	}
 
-			newData := types.NewData(height, block_hash, data_merkle, timestamp)
+	state.Mng().SetNextHeight(util.FromStringToUint64(ag.data[0].Height))
+	ag.data = nil
+}
 
-			// Listing aggreated info.
-			dataAggregate = append(dataAggregate, newData)
-		}
+// Send the aggregated blocks to the queue of batches.
+// It returns false if the context is done before the queue takes the batch.
+// The batch is kept, and the next height is moved back to its first block when the fetcher returns.
+func (ag *aggregator) send(ctx context.Context, a *types.App) bool {
+	latest := ag.data[len(ag.data)-1].Height
 
-		if len(dataAggregate) == count {
-			latest := dataAggregate[count-1].Height
+	util.LogInfo(util.BB("fin aggregate"))
+	util.LogInfo(util.BB("aggregated first block height=") + ag.data[0].Height)
+	util.LogInfo(util.BB("aggregated latest block height=") + latest)
 
-			util.LogInfo(util.BB("fin aggregate"))
-			util.LogInfo(util.BB("aggregated first block height=") + dataAggregate[0].Height)
-			util.LogInfo(util.BB("aggregated latest block height=") + latest)
+	// Anchoring older blocks such as backfill does not move the latest height of the contract backwards.
+	recorded := state.Mng().RecordedLatest()
+	if recorded > util.FromStringToUint64(latest) {
+		latest = util.FromUint64ToString(recorded)
+	}
 
-			newAnchoring := types.NewAncoring(dataAggregate, latest)
+	newAnchoring := types.NewAncoring(ag.data, latest)
 
-			a.Channels.AnchringTx <- newAnchoring
+	// The batch is not queued after the cancel, though the queue has room for it.
+	if ctx.Err() != nil {
+		return false
+	}
 
-			dataAggregate = nil
-		} else {
-			a.Channels.HttpClientStartSignal <- true
-		}
+	// The fetcher only waits when the queue of batches is full.
+	if len(a.Channels.AnchringTx) == cap(a.Channels.AnchringTx) {
+		util.LogWait("anchoring queue is full, waiting for in-flight transactions...")
+	}
+	select {
+	case a.Channels.AnchringTx <- newAnchoring:
+	case <-ctx.Done():
+		return false
 	}
 
+	ag.data = nil
+	return true
 }
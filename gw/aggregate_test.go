@@ -0,0 +1,83 @@
+package gw
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"gopkg.in/yaml.v3"
+)
+
+// The collect count which is reloaded to the value smaller than collected blocks sends them at the next block.
+func TestAggregateCountReloaded(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	err := InitBlockAdapter(testBlockApi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ag aggregator
+	for _, block := range env.priv.blocks[:2] {
+		if !ag.aggregate(context.Background(), a, block) {
+			t.Fatal("the block is not collected")
+		}
+	}
+	if len(a.Channels.AnchringTx) != 0 {
+		t.Fatal("the batch is sent before the collect count")
+	}
+
+	env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.CollectBlockCount = 1
+	})
+	defer env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.CollectBlockCount = testCollectCount
+	})
+
+	if !ag.aggregate(context.Background(), a, env.priv.blocks[2]) {
+		t.Fatal("the block is not collected")
+	}
+
+	select {
+	case anchoring := <-a.Channels.AnchringTx:
+		if got, want := batchHeights(anchoring), []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("heights of the batch = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("collected blocks are not sent after the collect count is reloaded")
+	}
+}
+
+// The collect count which is not positive is rejected by the reload.
+func TestReloadRejectsCollectCount(t *testing.T) {
+	env := startTestEnv(t)
+
+	for _, count := range []int{0, -1} {
+		appType := env.appType
+		appType.Config.Anchor.CollectBlockCount = count
+
+		bytes, err := yaml.Marshal(appType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		appFilePath := filepath.Join(t.TempDir(), "app.yaml")
+		err = os.WriteFile(appFilePath, bytes, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = app.AppFile().Reload(appFilePath)
+		if err == nil || !strings.Contains(err.Error(), "collect block count") {
+			t.Fatalf("reload of the count %d: err = %v, want the rejection", count, err)
+		}
+		if got := app.AppFile().Get().Config.Anchor.CollectBlockCount; got != testCollectCount {
+			t.Fatalf("collect block count = %d, want %d", got, testCollectCount)
+		}
+	}
+}
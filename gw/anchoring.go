@@ -1,52 +1,635 @@
 package gw
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
 	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	mauthz "github.com/Moonyongjung/xpla.go/core/authz"
+	"github.com/Moonyongjung/xpla.go/key"
 	xtypes "github.com/Moonyongjung/xpla.go/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultTxConfirmTimeout = 60000
+	confirmPollPeriod       = 1000
+	// Default max tx bytes of the mempool of the tendermint.
+	defaultMaxTxBytes = 1048576
 )
 
 // Send the transaction is anchoring message.
 // The message has aggregated blocks info of the private chain.
-func SendAnchoringTx(a *types.App, log string) {
-	channel := a.Channels.AnchringTx
+// The transaction is broadcasted by "sync" mode, so the sender does not wait until confirmed time.
+// Each account runs its own sender and the senders take batches from the same queue in turn,
+// so batches are spread across the accounts with the sequence of each account.
+// Failed batches which are returned by the confirmer are sent again with priority.
+// The sender runs as the component of the supervisor. Batches which are not sent when the sender fails
+// are kept, and they are sent at first after the sender is restarted.
+// The sender returns when the context is done, and the batch which is not broadcasted yet is kept as unsent.
+// Broadcasted transactions are kept in the state until they are confirmed.
+func SendAnchoringTx(a *types.App, sender *client.XplaClient, log string) func(ctx context.Context) error {
+	var unsent []types.Anchoring
+
+	return func(ctx context.Context) error {
+		// The XPLA client keeps the state of the latest message, so the sender owns its copy.
+		xplac := cloneClient(sender).WithBroadcastMode("sync")
+
+		// Fees are paid by the allowance of the granter, or the fee granter of submitters.
+		if feeGranter := feeGranterAddress(); feeGranter != "" {
+			granter, err := sdk.AccAddressFromBech32(feeGranter)
+			if err != nil {
+				return err
+			}
+			xplac.WithFeeGranter(granter)
+		}
+
+		for {
+			if len(unsent) == 0 {
+				anchoringTx, ok := nextAnchoringTx(ctx, a)
+				if !ok {
+					return nil
+				}
+				unsent = []types.Anchoring{anchoringTx}
+			}
+
+			pendingTxs, err := broadcastAnchoringTx(ctx, xplac, unsent[0])
+			for _, pendingTx := range pendingTxs {
+				// The confirmer does not take transactions after the context is done,
+				// so the transaction which is not handed over is confirmed by the next run from the state.
+				select {
+				case a.Channels.PendingTx <- pendingTx:
+				case <-ctx.Done():
+					util.LogWarning("the gateway is stopped before the anchoring tx is confirmed, confirm it at the next run.", util.BB("txhash=")+pendingTx.TxHash)
+				}
+			}
+
+			if err != nil {
+				var sendErr *sendError
+				if errors.As(err, &sendErr) {
+					unsent = append(sendErr.Unsent, unsent[1:]...)
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			unsent = unsent[1:]
+		}
+	}
+}
+
+// The error of broadcasting the batch.
+// If the batch is split, halves which are already sent are excluded from unsent batches.
+type sendError struct {
+	Unsent []types.Anchoring
+	Err    error
+}
+
+func (e *sendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *sendError) Unwrap() error {
+	return e.Err
+}
+
+// Take the next batch to send.
+// Failed batches are taken with priority, and they are still sent while the gateway is paused,
+// so transactions in flight are finished. New batches are not taken while the gateway is paused.
+// It returns false if the context is done before the batch is taken.
+func nextAnchoringTx(ctx context.Context, a *types.App) (types.Anchoring, bool) {
 	for {
+		paused, changed := PauseMng().current()
+		if paused {
+			select {
+			case anchoringTx := <-a.Channels.RetryTx:
+				return anchoringTx, true
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return types.Anchoring{}, false
+			}
+		}
+
+		select {
+		case anchoringTx := <-a.Channels.RetryTx:
+			return anchoringTx, true
+		default:
+		}
+
 		select {
-		case anchoringTx := <-channel:
-			addr := app.AppFile().Get().Contract.Address
+		case anchoringTx := <-a.Channels.RetryTx:
+			return anchoringTx, true
+		case anchoringTx := <-a.Channels.AnchringTx:
+			return anchoringTx, true
+		case <-changed:
+		case <-ctx.Done():
+			return types.Anchoring{}, false
+		}
+	}
+}
+
+// Sign and broadcast the anchoring transaction.
+// If the transaction exceeds the max tx bytes or the max tx gas, the batch is split in half and each is sent by its own transaction.
+// If broadcasting is failed, the sequence is synchronized with the chain and the transaction is sent again.
+// Retries stop when the context is done, and the batch is returned as unsent.
+func broadcastAnchoringTx(ctx context.Context, xplac *client.XplaClient, anchoringTx types.Anchoring) ([]types.PendingTx, error) {
+	addr := app.AppFile().Get().Contract.Address
+	pubConf := app.AppFile().Get().Config.PublicChain
+
+	// Gas settings can be changed by reloading the config.
+	xplac.WithGasAdjustment(pubConf.GasAdj).WithGasLimit(pubConf.GasLimit)
+
+	user, err := senderAddress(xplac)
+	if err != nil {
+		return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: err}
+	}
+
+	// The batch which is sent again after newer batches keeps the latest height of them,
+	// so the contract which does not check the latest height does not move it backwards by the retry.
+	// Transactions of several accounts may be included out of order, and it is checked by the contract.
+	if submitted := state.Mng().SubmittedLatest(); submitted > util.FromStringToUint64(anchoringTx.Latest) {
+		anchoringTx.Latest = util.FromUint64ToString(submitted)
+	}
+
+	execMsg, err := anchoringMsg(anchoringTx)
+	if err != nil {
+		return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: err}
+	}
+
+	// The transaction is bigger than the message, so the message which exceeds the limit is split before signing.
+	if limit, size, max, ok := exceededTxLimit(len(execMsg), 0); ok && len(anchoringTx.Data) > 1 {
+		return splitAnchoringTx(ctx, xplac, anchoringTx, limit, size, max)
+	}
+
+	for {
+		seq := util.FromUint64ToString(state.Mng().Sequence(user))
 
-			bytes, err := util.JsonMarshalData(anchoringTx)
+		var txbytes []byte
+		err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+			var err error
+			txbytes, err = signAnchoringTx(xplac, addr, seq, execMsg)
+			return err
+		})
+		if err != nil {
+			return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: err}
+		}
+
+		gas, err := txGas(txbytes)
+		if err != nil {
+			return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: err}
+		}
+
+		if limit, size, max, ok := exceededTxLimit(len(txbytes), gas); ok {
+			if len(anchoringTx.Data) > 1 {
+				return splitAnchoringTx(ctx, xplac, anchoringTx, limit, size, max)
+			}
+			warnSingleBlockLimit(anchoringTx, len(txbytes), gas)
+		}
+
+		hash := txHash(txbytes)
+
+		// The batch of the memo sink is traceable by the pending entry even if the gateway stops before it is confirmed.
+		if IsMemoSink() {
+			err = recordMemoIndex(types.MemoIndexPending, anchoringTx, hash)
 			if err != nil {
-				util.LogErr(types.ErrGw, err)
-				panic(err)
+				return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: err}
+			}
+		}
+
+		util.LogWait("send anchoring tx...", util.BB("account=")+user, util.BB("sequence=")+seq)
+		err = broadcastWithFailover(xplac, txbytes, hash, false)
+
+		// If all endpoints of the public chain fail, the same transaction is sent again
+		// instead of signing the batch with the new sequence.
+		for err != nil && isEndpointErr(err) {
+			util.LogWarning("all endpoints of the public chain are failed, broadcast again:", err)
+			if !sleepContext(ctx, time.Millisecond*time.Duration(waitingBlockTime)) {
+				return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: ctx.Err()}
 			}
 
-			executeMsg := xtypes.ExecuteMsg{
-				ContractAddress: addr,
-				Amount:          "0",
-				ExecMsg:         `{"anchoring":` + string(bytes) + `}`,
+			err = broadcastWithFailover(xplac, txbytes, hash, true)
+		}
+
+		if err != nil {
+			util.LogWarning("failed to broadcast anchoring tx, sync the sequence and retry:", err)
+
+			// The refused transaction is not included, so its pending entry is failed instead of being resolved at the next start.
+			if IsMemoSink() {
+				recordErr := recordMemoIndex(types.MemoIndexFailed, anchoringTx, hash)
+				if recordErr != nil {
+					util.LogWarning("failed to record the failed memo tx:", recordErr)
+				}
 			}
 
-			xplac := a.PubClient.WithSequence(SequenceMng().NowSequence())
-			txbytes, err := xplac.ExecuteContract(executeMsg).CreateAndSignTx()
+			if !sleepContext(ctx, time.Millisecond*time.Duration(waitingBlockTime)) {
+				return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: ctx.Err()}
+			}
+
+			err = syncSequence(xplac)
 			if err != nil {
-				util.LogErr(types.ErrGw, err)
-				panic(err)
+				return nil, &sendError{Unsent: []types.Anchoring{anchoringTx}, Err: err}
+			}
+			continue
+		}
+
+		state.Mng().IncreaseSequence(user)
+		state.Mng().RaiseSubmittedLatest(util.FromStringToUint64(anchoringTx.Latest))
+
+		pendingTx := types.NewPendingTx(anchoringTx, user, seq, hash)
+		trackPendingTx(pendingTx)
+
+		return []types.PendingTx{pendingTx}, nil
+	}
+}
+
+// Get the address which pays fees of anchoring transactions by the fee allowance.
+// In the authz mode, it is the granter if the fee grant is set.
+func feeGranterAddress() string {
+	authzConf := app.AppFile().Get().Config.Anchor.Authz
+	if authzConf.Granter != "" {
+		if authzConf.FeeGrant {
+			return authzConf.Granter
+		}
+		return ""
+	}
+	return authzConf.FeeGranter
+}
+
+// Find the limit which the anchoring transaction exceeds by its bytes or gas.
+// Limits can be changed by reloading the config.
+func exceededTxLimit(txBytes int, gas uint64) (string, int, int, bool) {
+	anchorConf := app.AppFile().Get().Config.Anchor
+
+	maxTxBytes := anchorConf.MaxTxBytes
+	if maxTxBytes <= 0 {
+		maxTxBytes = defaultMaxTxBytes
+	}
+
+	switch {
+	case txBytes > maxTxBytes:
+		return "max tx bytes", txBytes, maxTxBytes, true
+	case anchorConf.MaxTxGas > 0 && gas > anchorConf.MaxTxGas:
+		return "max tx gas", int(gas), int(anchorConf.MaxTxGas), true
+	}
+	return "", 0, 0, false
+}
+
+// The batch of the single block can not be split, so it is sent anyway.
+func warnSingleBlockLimit(anchoringTx types.Anchoring, txBytes int, gas uint64) {
+	util.LogWarning("anchoring tx of the single block exceeds the limit, send it anyway.",
+		util.BB("height=")+anchoringTx.Data[0].Height, util.BB("tx bytes=")+strconv.Itoa(txBytes), util.BB("gas=")+util.FromUint64ToString(gas))
+}
+
+func logSplitAnchoring(first, second types.Anchoring, limit string, size, max int) {
+	util.LogInfo(util.BB("split anchoring batch by ")+limit, util.BB("size=")+strconv.Itoa(size), util.BB("limit=")+strconv.Itoa(max),
+		util.BB("heights=")+first.Data[0].Height+"~"+first.Data[len(first.Data)-1].Height+", "+second.Data[0].Height+"~"+second.Data[len(second.Data)-1].Height)
+}
+
+// Split the batch in half, and sign and broadcast each.
+// If the first half fails, the second half is also unsent.
+func splitAnchoringTx(ctx context.Context, xplac *client.XplaClient, anchoringTx types.Anchoring, limit string, size, max int) ([]types.PendingTx, error) {
+	first, second := splitAnchoring(anchoringTx)
+	logSplitAnchoring(first, second, limit, size, max)
+
+	firstPendingTxs, err := broadcastAnchoringTx(ctx, xplac, first)
+	if err != nil {
+		var sendErr *sendError
+		if errors.As(err, &sendErr) {
+			return firstPendingTxs, &sendError{Unsent: append(sendErr.Unsent, second), Err: sendErr.Err}
+		}
+		return firstPendingTxs, err
+	}
+
+	secondPendingTxs, err := broadcastAnchoringTx(ctx, xplac, second)
+	return append(firstPendingTxs, secondPendingTxs...), err
+}
+
+// Split the batch in half.
+// The latest height of each half is its last height, so the contract records the last height of the first half
+// as the latest height when the first half lands, and the latest height moves to the last height of the second half after it lands.
+// The contract only records the latest height which is higher than the recorded one, so the latest height never moves backwards
+// even if the second half lands first.
+// If the latest height of the batch is the recorded latest height of the contract which is higher than the batch, both halves keep it.
+func splitAnchoring(anchoring types.Anchoring) (types.Anchoring, types.Anchoring) {
+	half := len(anchoring.Data) / 2
+	firstData := append([]types.Data{}, anchoring.Data[:half]...)
+	secondData := append([]types.Data{}, anchoring.Data[half:]...)
+
+	latest := func(data []types.Data) string {
+		last := data[len(data)-1].Height
+		if util.FromStringToUint64(anchoring.Latest) > util.FromStringToUint64(anchoring.Data[len(anchoring.Data)-1].Height) {
+			return anchoring.Latest
+		}
+		return last
+	}
+
+	return types.NewAncoring(firstData, latest(firstData)), types.NewAncoring(secondData, latest(secondData))
+}
+
+// Make the execute message of the anchoring, or the commitment of the memo sink.
+// The message is compact JSON, because the size of the transaction is limited.
+func anchoringMsg(anchoringTx types.Anchoring) (string, error) {
+	if IsMemoSink() {
+		commitment, _, err := MemoCommitment(app.AppFile().Get().Config.PrivateChain.ChainID, anchoringTx.Data)
+		return commitment, err
+	}
+
+	bytes, err := json.Marshal(anchoringTx)
+	if err != nil {
+		return "", err
+	}
+
+	return `{"anchoring":` + string(bytes) + `}`, nil
+}
+
+// Get the fee of the signed transaction.
+// Only the auth info is decoded, because the codec of the XPLA client does not register messages of the wasm module.
+func decodeTxFee(txbytes []byte) (*txtypes.Fee, error) {
+	var txRaw txtypes.TxRaw
+	err := txRaw.Unmarshal(txbytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var authInfo txtypes.AuthInfo
+	err = authInfo.Unmarshal(txRaw.AuthInfoBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if authInfo.Fee == nil {
+		return nil, errors.New("the tx has no fee")
+	}
+
+	return authInfo.Fee, nil
+}
+
+// Get the gas limit of the signed transaction.
+func txGas(txbytes []byte) (uint64, error) {
+	fee, err := decodeTxFee(txbytes)
+	if err != nil {
+		return 0, err
+	}
+	return fee.GasLimit, nil
+}
+
+// Sign the anchoring transaction by the sink.
+func signAnchoringTx(xplac *client.XplaClient, contractAddr, seq, msg string) ([]byte, error) {
+	if IsMemoSink() {
+		return signMemoTx(xplac, seq, msg)
+	}
+	return withAnchoringMsg(xplac.WithSequence(seq), contractAddr, msg).CreateAndSignTx()
+}
+
+// Set the anchoring message to the XPLA client.
+// In the authz mode, the execute message of the granter is wrapped in MsgExec,
+// and the account of the gateway sends it as the grantee.
+func withAnchoringMsg(xplac *client.XplaClient, contractAddr string, execMsg string) *client.XplaClient {
+	granter := app.AppFile().Get().Config.Anchor.Authz.Granter
+	if granter == "" {
+		executeMsg := xtypes.ExecuteMsg{
+			ContractAddress: contractAddr,
+			Amount:          "0",
+			ExecMsg:         execMsg,
+		}
+
+		return xplac.ExecuteContract(executeMsg)
+	}
+
+	grantee := sdk.AccAddress(xplac.GetPrivateKey().PubKey().Address())
+	msg := authz.NewMsgExec(grantee, []sdk.Msg{
+		&wasmtypes.MsgExecuteContract{
+			Sender:   granter,
+			Contract: contractAddr,
+			Msg:      wasmtypes.RawContractMessage(execMsg),
+			Funds:    sdk.NewCoins(),
+		},
+	})
+
+	xplac.Module = mauthz.AuthzModule
+	xplac.MsgType = mauthz.AuthzExecMsgType
+	xplac.Msg = msg
+
+	return xplac
+}
+
+// Record the broadcasted transaction in the state until it is confirmed or its batch is sent again,
+// so the next run confirms the transaction which is left when the gateway stops.
+// The transaction of the memo sink is recorded by the memo index instead.
+func trackPendingTx(pendingTx types.PendingTx) {
+	if IsMemoSink() {
+		return
+	}
+
+	bytes, err := json.Marshal(pendingTx)
+	if err != nil {
+		util.LogWarning("failed to record the pending tx:", err)
+		return
+	}
+
+	state.Mng().AddPendingTx(pendingTx.TxHash, bytes)
+}
+
+// Get transactions which are left in the state by the previous run, in the order of heights.
+func restorePendingTxs() []types.PendingTx {
+	var pendingTxs []types.PendingTx
+	for hash, bytes := range state.Mng().PendingTxs() {
+		var pendingTx types.PendingTx
+		err := json.Unmarshal(bytes, &pendingTx)
+		if err != nil || len(pendingTx.Anchoring.Data) == 0 {
+			util.LogWarning("invalid pending tx in the state, txhash="+hash, err)
+			state.Mng().RemovePendingTx(hash)
+			continue
+		}
+		pendingTxs = append(pendingTxs, pendingTx)
+	}
+
+	sort.Slice(pendingTxs, func(i, j int) bool {
+		return util.FromStringToUint64(pendingTxs[i].Anchoring.Data[0].Height) < util.FromStringToUint64(pendingTxs[j].Anchoring.Data[0].Height)
+	})
+
+	if len(pendingTxs) != 0 {
+		util.LogInfo(util.BB("confirm pending txs of the previous run"), util.BB("count=")+strconv.Itoa(len(pendingTxs)))
+	}
+
+	return pendingTxs
+}
+
+// Confirm the broadcasted anchoring transactions in the sequence order.
+// Restored transactions of the previous run are confirmed at first.
+// The batch is sent again if the transaction is failed or not included in a block until the timeout.
+// The confirmer runs as the component of the supervisor, and the transaction which is being confirmed
+// when the confirmer fails is confirmed again after it is restarted.
+// The confirmer returns when the context is done, and the transaction which is being confirmed is kept in the state.
+func confirmAnchoringTx(a *types.App, restored []types.PendingTx) func(ctx context.Context) error {
+	var confirming *types.PendingTx
+
+	return func(ctx context.Context) error {
+		xplac := cloneClient(a.PubClient)
+
+		for {
+			if confirming == nil && len(restored) != 0 {
+				confirming = &restored[0]
+				restored = restored[1:]
+			}
+
+			if confirming == nil {
+				select {
+				case pendingTx, ok := <-a.Channels.PendingTx:
+					if !ok {
+						return nil
+					}
+					confirming = &pendingTx
+				case <-ctx.Done():
+					return nil
+				}
 			}
 
-			util.LogWait("send anchoring tx...")
-			// The mode of broadcasting is "block" because of waiting until confirmed time.
-			_, err = xplac.BroadcastBlock(txbytes)
+			err := confirmPendingTx(ctx, a, xplac, *confirming)
+			if ctx.Err() != nil {
+				return nil
+			}
 			if err != nil {
-				util.LogErr(types.ErrGw, err)
-				panic(err)
+				return err
 			}
+			confirming = nil
+		}
+	}
+}
+
+func confirmPendingTx(ctx context.Context, a *types.App, xplac *client.XplaClient, pendingTx types.PendingTx) error {
+	timeout := app.AppFile().Get().Config.Anchor.TxConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultTxConfirmTimeout
+	}
+
+	first := pendingTx.Anchoring.Data[0].Height
+	latest := pendingTx.Anchoring.Data[len(pendingTx.Anchoring.Data)-1].Height
+
+	txRes, err := waitTx(ctx, xplac, pendingTx.TxHash, timeout)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		util.LogWarning("anchoring tx is not confirmed, send again.", util.BB("txhash=")+pendingTx.TxHash, err)
+		return retryPendingTx(ctx, a, pendingTx)
+	}
+
+	if txRes.TxResponse.Code != 0 {
+		util.LogWarning("anchoring tx is failed, send again.", util.BB("txhash=")+pendingTx.TxHash, txRes.TxResponse.RawLog)
+		return retryPendingTx(ctx, a, pendingTx)
+	}
+
+	if IsMemoSink() {
+		err = recordMemoIndex(types.MemoIndexConfirmed, pendingTx.Anchoring, pendingTx.TxHash)
+		if err != nil {
+			return err
+		}
+	}
 
-			util.LogInfo(util.BB("anchoring success"))
+	state.Mng().RemovePendingTx(pendingTx.TxHash)
+	AccountStatMng().AddTx(pendingTx.Sender, txRes)
+	util.LogInfo(util.BB("anchoring success"), util.BB("heights=")+first+"~"+latest, util.BB("txhash=")+pendingTx.TxHash)
+
+	if a.Channels.ConfirmedTx != nil {
+		select {
+		case a.Channels.ConfirmedTx <- pendingTx.Anchoring:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
 
-			SequenceMng().AddSequence()
-			a.Channels.HttpClientStartSignal <- true
+// Send the batch of the failed transaction again.
+// The pending entry of the memo sink is failed, and it is resolved when the gateway starts if it is not written.
+func retryPendingTx(ctx context.Context, a *types.App, pendingTx types.PendingTx) error {
+	if IsMemoSink() {
+		err := recordMemoIndex(types.MemoIndexFailed, pendingTx.Anchoring, pendingTx.TxHash)
+		if err != nil {
+			util.LogWarning("failed to record the failed memo tx:", err)
 		}
 	}
+
+	select {
+	case a.Channels.RetryTx <- pendingTx.Anchoring:
+		state.Mng().RemovePendingTx(pendingTx.TxHash)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait until the transaction is included in a block.
+// It stops waiting when the context is done.
+func waitTx(ctx context.Context, xplac *client.XplaClient, txHash string, timeout int) (types.QueryTxResponse, error) {
+	var txRes types.QueryTxResponse
+	deadline := time.Now().Add(time.Millisecond * time.Duration(timeout))
+
+	for {
+		var res string
+		err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+			var err error
+			res, err = xplac.Tx(xtypes.QueryTxMsg{Value: txHash}).Query()
+			return err
+		})
+		if err == nil {
+			responseData := util.JsonUnmarshalData(&txRes, []byte(res))
+			mapstructure.Decode(responseData, &txRes)
+
+			return txRes, nil
+		}
+
+		if time.Now().After(deadline) {
+			return txRes, err
+		}
+		if !sleepContext(ctx, time.Millisecond*time.Duration(confirmPollPeriod)) {
+			return txRes, ctx.Err()
+		}
+	}
+}
+
+// Synchronize the sequence number of the anchor account with the chain.
+func syncSequence(xplac *client.XplaClient) error {
+	user, err := senderAddress(xplac)
+	if err != nil {
+		return err
+	}
+
+	seqRes, err := querySequence(xplac, user)
+	if err != nil {
+		return err
+	}
+
+	state.Mng().SetSequence(user, util.FromStringToUint64(util.ParsingQueryAccount(seqRes)))
+
+	return nil
+}
+
+// Get the address of the account which sends the transaction.
+func senderAddress(xplac *client.XplaClient) (string, error) {
+	return key.Bech32AddrString(xplac.GetPrivateKey())
+}
+
+// Copy the XPLA client.
+// The client records the message of each request, so goroutines do not share the same one.
+func cloneClient(xplac *client.XplaClient) *client.XplaClient {
+	cloned := *xplac
+	return &cloned
 }
@@ -0,0 +1,496 @@
+package gw
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw/db"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla-anchor/util/fsutil"
+)
+
+const (
+	defaultAuditRecentPeriod     = 10000
+	defaultAuditRecheckTimeout   = 600000
+	defaultAuditHistoricalPeriod = 600000
+	defaultAuditHistoricalSample = 100
+	auditRecentBatchSize         = 1000
+	auditKindRecent              = "recent"
+	auditKindHistorical          = "historical"
+	auditResultMismatch          = "mismatch"
+	auditResultMissing           = "missing"
+)
+
+var auditInstance *Audit
+var auditOnce sync.Once
+
+// Results of the audit.
+// They are reported by logs, metrics and DB if it is set.
+type Audit struct {
+	mu         sync.Mutex
+	audited    uint64
+	rechecks   map[uint64]time.Time
+	checked    map[string]uint64
+	matched    uint64
+	mismatched map[string]uint64
+	missing    uint64
+	failed     uint64
+	db         *sql.DB
+}
+
+func AuditMng() *Audit {
+	auditOnce.Do(func() {
+		auditInstance = &Audit{
+			rechecks:   make(map[uint64]time.Time),
+			checked:    make(map[string]uint64),
+			mismatched: make(map[string]uint64),
+		}
+	})
+	return auditInstance
+}
+
+// Save inconsistent results to DB.
+func (au *Audit) UseDb(auditDb *sql.DB) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	au.db = auditDb
+}
+
+// Get the highest height which is audited as the recent batch.
+func (au *Audit) Audited() uint64 {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	return au.audited
+}
+
+func (au *Audit) setAudited(height uint64) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	au.audited = height
+}
+
+// Get heights which are checked again by the audit of recent batches.
+func (au *Audit) Rechecks() []uint64 {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	heights := make([]uint64, 0, len(au.rechecks))
+	for height := range au.rechecks {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	return heights
+}
+
+// Pick results of recent batches which are reported.
+// Batches of several senders are recorded out of order, and requests can fail for a while,
+// so missing and failed heights are checked again until the recheck timeout before they are reported.
+func (au *Audit) recheck(results []types.VerifyResult, timeout time.Duration) []types.VerifyResult {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	now := time.Now()
+
+	var reported []types.VerifyResult
+	for _, result := range results {
+		height := util.FromStringToUint64(result.Height)
+		if result.Missing || result.Error != "" {
+			first, ok := au.rechecks[height]
+			if !ok {
+				au.rechecks[height] = now
+				continue
+			}
+			if now.Sub(first) < timeout {
+				continue
+			}
+		}
+
+		delete(au.rechecks, height)
+		reported = append(reported, result)
+	}
+
+	return reported
+}
+
+// Drop results of heights which are checked again by the audit of recent batches.
+func (au *Audit) skipRechecks(results []types.VerifyResult) []types.VerifyResult {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	var picked []types.VerifyResult
+	for _, result := range results {
+		if _, ok := au.rechecks[util.FromStringToUint64(result.Height)]; !ok {
+			picked = append(picked, result)
+		}
+	}
+
+	return picked
+}
+
+func (au *Audit) progress() types.AuditProgress {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	rechecks := make(map[string]int64)
+	for height, first := range au.rechecks {
+		rechecks[util.FromUint64ToString(height)] = first.UnixMilli()
+	}
+
+	return types.NewAuditProgress(util.FromUint64ToString(au.audited), rechecks)
+}
+
+func (au *Audit) restore(progress types.AuditProgress) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	au.audited = util.FromStringToUint64(progress.AuditedHeight)
+	for height, first := range progress.Rechecks {
+		au.rechecks[util.FromStringToUint64(height)] = time.UnixMilli(first)
+	}
+}
+
+func (au *Audit) record(kind string, results []types.VerifyResult) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	for _, result := range results {
+		au.checked[kind]++
+
+		var auditResult, detail string
+		switch {
+		case result.Error != "":
+			au.failed++
+			continue
+		case result.Missing:
+			au.missing++
+			auditResult = auditResultMissing
+		case len(result.Mismatches) != 0:
+			for _, field := range result.Mismatches {
+				au.mismatched[field]++
+			}
+			auditResult = auditResultMismatch
+			detail = strings.Join(result.Mismatches, ",")
+		default:
+			au.matched++
+			continue
+		}
+
+		if au.db != nil {
+			err := db.SaveAuditResult(au.db, result.Height, kind, auditResult, detail)
+			if err != nil {
+				util.LogWarning("failed to save the audit result:", err)
+			}
+		}
+	}
+}
+
+// Metrics of the audit by the Prometheus text format.
+func (au *Audit) Metrics() string {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+
+	var metrics strings.Builder
+
+	metrics.WriteString("# HELP anchor_audit_audited_height The highest height which is audited as the recent batch.\n")
+	metrics.WriteString("# TYPE anchor_audit_audited_height gauge\n")
+	fmt.Fprintf(&metrics, "anchor_audit_audited_height %d\n", au.audited)
+
+	metrics.WriteString("# HELP anchor_audit_rechecks The number of missing or failed heights which are checked again.\n")
+	metrics.WriteString("# TYPE anchor_audit_rechecks gauge\n")
+	fmt.Fprintf(&metrics, "anchor_audit_rechecks %d\n", len(au.rechecks))
+
+	metrics.WriteString("# HELP anchor_audit_checked_total The number of audited heights.\n")
+	metrics.WriteString("# TYPE anchor_audit_checked_total counter\n")
+	for _, kind := range []string{auditKindRecent, auditKindHistorical} {
+		fmt.Fprintf(&metrics, "anchor_audit_checked_total{kind=\"%s\"} %d\n", kind, au.checked[kind])
+	}
+
+	metrics.WriteString("# HELP anchor_audit_matched_total The number of heights which are consistent.\n")
+	metrics.WriteString("# TYPE anchor_audit_matched_total counter\n")
+	fmt.Fprintf(&metrics, "anchor_audit_matched_total %d\n", au.matched)
+
+	metrics.WriteString("# HELP anchor_audit_mismatched_total The number of mismatched fields.\n")
+	metrics.WriteString("# TYPE anchor_audit_mismatched_total counter\n")
+	for _, field := range types.VerifyFields {
+		fmt.Fprintf(&metrics, "anchor_audit_mismatched_total{field=\"%s\"} %d\n", field, au.mismatched[field])
+	}
+
+	metrics.WriteString("# HELP anchor_audit_missing_total The number of heights which are not recorded.\n")
+	metrics.WriteString("# TYPE anchor_audit_missing_total counter\n")
+	fmt.Fprintf(&metrics, "anchor_audit_missing_total %d\n", au.missing)
+
+	metrics.WriteString("# HELP anchor_audit_failed_total The number of heights which are failed to be audited.\n")
+	metrics.WriteString("# TYPE anchor_audit_failed_total counter\n")
+	fmt.Fprintf(&metrics, "anchor_audit_failed_total %d\n", au.failed)
+
+	return metrics.String()
+}
+
+// Run the audit until it fails.
+// The audit does not need the anchoring key, so it can run as the independent service alongside the gateway.
+// Recent batches are audited from the start height if it is set.
+// Otherwise the audit resumes by the progress file, or starts from the next height of the recorded latest height.
+func StartAudit(a *types.App, contractAddr, blockApi, fromHeight, progressFile string) error {
+	util.LogInfo(util.BB("audit, target anchor contract=") + contractAddr)
+
+	err := InitBlockAdapter(blockApi)
+	if err != nil {
+		return err
+	}
+
+	progress, err := loadAuditProgress(progressFile)
+	if err != nil {
+		return err
+	}
+	AuditMng().restore(progress)
+
+	switch {
+	case fromHeight != "":
+		AuditMng().setAudited(util.FromStringToUint64(fromHeight) - 1)
+	case progress.AuditedHeight != "":
+		util.LogInfo(util.BB("resume audit"), util.BB("audited height=")+progress.AuditedHeight, util.BB("rechecks=")+util.ToString(len(progress.Rechecks), "0"))
+	default:
+		latestHeight, err := QueryRecordedLatestHeight(a.PubClient, contractAddr)
+		if err != nil {
+			return err
+		}
+		AuditMng().setAudited(util.FromStringToUint64(latestHeight))
+	}
+
+	metricsAddr := app.AppFile().Get().Config.Audit.MetricsListenAddr
+	if metricsAddr != "" {
+		go serveAuditMetrics(metricsAddr)
+	}
+
+	supervisor := NewSupervisor(context.Background())
+	supervisor.Go("recent auditor", func(ctx context.Context) error {
+		return auditRecent(ctx, a, contractAddr, blockApi, progressFile)
+	})
+	supervisor.Go("historical auditor", func(ctx context.Context) error {
+		return auditHistorical(ctx, a, contractAddr, blockApi)
+	})
+	supervisor.Go("endpoint checker", func(ctx context.Context) error {
+		checkPrivEndpoints(ctx, blockApi)
+		return nil
+	})
+
+	<-supervisor.Done()
+	supervisor.Stop(nil)
+
+	return supervisor.Err()
+}
+
+// Audit recent batches as soon as they are recorded.
+// The audited height and rechecks are saved to the progress file after each round,
+// so heights which are recorded while the audit is stopped are audited after the restart.
+func auditRecent(ctx context.Context, a *types.App, contractAddr, blockApi, progressFile string) error {
+	xplac := cloneClient(a.PubClient)
+
+	for {
+		auditConf := app.AppFile().Get().Config.Audit
+
+		period := auditConf.RecentPeriod
+		if period <= 0 {
+			period = defaultAuditRecentPeriod
+		}
+
+		timeout := auditConf.RecheckTimeout
+		if timeout <= 0 {
+			timeout = defaultAuditRecheckTimeout
+		}
+
+		if IsMemoSink() {
+			err := MemoIndexMng().Reload()
+			if err != nil {
+				return err
+			}
+		}
+
+		latestHeight, err := QueryRecordedLatestHeight(xplac, contractAddr)
+		if err != nil {
+			util.LogWarning("failed to query the latest block height for the audit:", err)
+		}
+
+		latest := util.FromStringToUint64(latestHeight)
+		audited := AuditMng().Audited()
+
+		// Many heights are audited by several rounds, so progress is reported and kept.
+		toHeight := audited
+		if err == nil && latest > audited {
+			toHeight = latest
+			if toHeight-audited > auditRecentBatchSize {
+				toHeight = audited + auditRecentBatchSize
+			}
+		}
+
+		// Rechecks are audited again with new heights.
+		heights := AuditMng().Rechecks()
+		if toHeight > audited {
+			heights = append(heights, VerifyTargetHeights(audited+1, toHeight, 0)...)
+		}
+
+		if len(heights) != 0 {
+			results := VerifyHeights(ctx, a, contractAddr, blockApi, heights, auditConf.Concurrency, auditConf.Rate)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			reported := AuditMng().recheck(results, time.Millisecond*time.Duration(timeout))
+			AuditMng().record(auditKindRecent, reported)
+			AuditMng().setAudited(toHeight)
+
+			err = saveAuditProgress(progressFile, AuditMng().progress())
+			if err != nil {
+				util.LogWarning("failed to save the progress of the audit:", err)
+			}
+
+			logAuditResults(auditKindRecent, heights[0], toHeight, reported, len(AuditMng().Rechecks()))
+		}
+
+		// Only rechecks are left, so they are audited again after the period.
+		if toHeight == audited {
+			if !sleepContext(ctx, time.Millisecond*time.Duration(period)) {
+				return nil
+			}
+		}
+	}
+}
+
+// Audit random heights which are already audited, so rewrites of the private chain after anchoring are caught.
+func auditHistorical(ctx context.Context, a *types.App, contractAddr, blockApi string) error {
+	for {
+		auditConf := app.AppFile().Get().Config.Audit
+
+		period := auditConf.HistoricalPeriod
+		if period <= 0 {
+			period = defaultAuditHistoricalPeriod
+		}
+
+		sample := auditConf.HistoricalSample
+		if sample <= 0 {
+			sample = defaultAuditHistoricalSample
+		}
+
+		if !sleepContext(ctx, time.Millisecond*time.Duration(period)) {
+			return nil
+		}
+
+		audited := AuditMng().Audited()
+		if audited == 0 {
+			continue
+		}
+
+		results := VerifyHeights(ctx, a, contractAddr, blockApi, VerifyTargetHeights(1, audited, sample), auditConf.Concurrency, auditConf.Rate)
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Heights of rechecks are reported by the audit of recent batches.
+		results = AuditMng().skipRechecks(results)
+		AuditMng().record(auditKindHistorical, results)
+
+		logAuditResults(auditKindHistorical, 1, audited, results, 0)
+	}
+}
+
+// Log the summary of results of the audit.
+// Heights which are failed to be checked are not able to be judged, but they are warned because they are not audited.
+func logAuditResults(kind string, fromHeight, toHeight uint64, results []types.VerifyResult, rechecks int) {
+	summary := types.NewVerifySummary(util.FromUint64ToString(fromHeight), util.FromUint64ToString(toHeight), kind == auditKindHistorical)
+	for _, result := range results {
+		summary.Add(result)
+	}
+
+	fields := []interface{}{
+		util.BB(kind + " audit"),
+		util.BB("heights=") + summary.FromHeight + "~" + summary.ToHeight,
+		util.BB("checked=") + util.ToString(summary.Checked, "0"),
+		util.BB("mismatched=") + util.ToString(summary.Mismatched, "0"),
+		util.BB("missing=") + util.ToString(summary.Missing, "0"),
+		util.BB("failed=") + util.ToString(summary.Failed, "0"),
+	}
+	if kind == auditKindRecent {
+		fields = append(fields, util.BB("rechecks=")+util.ToString(rechecks, "0"))
+	}
+
+	switch {
+	case summary.Mismatched != 0 || summary.Missing != 0:
+		util.LogWarning(append([]interface{}{util.R("NOT CONSISTENT")}, fields...)...)
+	case summary.Failed != 0:
+		util.LogWarning(append([]interface{}{"failed to audit heights=" + strings.Join(summary.FailedHeights, ",")}, fields...)...)
+	default:
+		util.LogInfo(fields...)
+	}
+}
+
+// Load the progress of the audit.
+// An empty progress is returned if the file does not exist.
+func loadAuditProgress(progressFile string) (types.AuditProgress, error) {
+	progress := types.NewAuditProgress("", nil)
+
+	bytes, err := os.ReadFile(progressFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return progress, nil
+		}
+		return progress, err
+	}
+
+	err = json.Unmarshal(bytes, &progress)
+	if err != nil {
+		return progress, err
+	}
+
+	return progress, nil
+}
+
+// Save the progress of the audit.
+func saveAuditProgress(progressFile string, progress types.AuditProgress) error {
+	bytes, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(progressFile, bytes)
+}
+
+// Serve metrics of the audit.
+func serveAuditMetrics(metricsAddr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte(AuditMng().Metrics()))
+	})
+
+	server := &http.Server{
+		Addr:        metricsAddr,
+		Handler:     mux,
+		ReadTimeout: time.Second * adminReadTimeout,
+	}
+
+	util.LogInfo(util.BB("audit metrics endpoint=") + metricsAddr)
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		util.LogWarning("audit metrics endpoint is stopped:", err)
+	}
+}
@@ -0,0 +1,121 @@
+package gw
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+)
+
+func newTestAudit() *Audit {
+	return &Audit{
+		rechecks:   make(map[uint64]time.Time),
+		checked:    make(map[string]uint64),
+		mismatched: make(map[string]uint64),
+	}
+}
+
+func testVerifyResult(height string, missing bool, errMsg string) types.VerifyResult {
+	result := types.NewVerifyResult(height)
+	result.Missing = missing
+	result.Error = errMsg
+	return result
+}
+
+func reportedHeights(results []types.VerifyResult) []string {
+	var heights []string
+	for _, result := range results {
+		heights = append(heights, result.Height)
+	}
+	return heights
+}
+
+// Missing and failed heights are checked again, and reported only after the recheck timeout.
+func TestAuditRecheck(t *testing.T) {
+	au := newTestAudit()
+
+	results := []types.VerifyResult{
+		testVerifyResult("1", false, ""),
+		testVerifyResult("2", true, ""),
+		testVerifyResult("3", false, "connection refused"),
+	}
+
+	reported := au.recheck(results, time.Hour)
+	if got, want := reportedHeights(reported), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reported heights = %v, want %v", got, want)
+	}
+	if got, want := au.Rechecks(), []uint64{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rechecks = %v, want %v", got, want)
+	}
+
+	// The batch of the height 2 is recorded, but the request of the height 3 is failed again.
+	results = []types.VerifyResult{
+		testVerifyResult("2", false, ""),
+		testVerifyResult("3", false, "connection refused"),
+	}
+
+	reported = au.recheck(results, time.Hour)
+	if got, want := reportedHeights(reported), []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reported heights = %v, want %v", got, want)
+	}
+	if got, want := au.Rechecks(), []uint64{3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rechecks = %v, want %v", got, want)
+	}
+
+	// The historical audit does not report heights which are checked again.
+	picked := au.skipRechecks([]types.VerifyResult{testVerifyResult("3", false, "connection refused"), testVerifyResult("4", true, "")})
+	if got, want := reportedHeights(picked), []string{"4"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("historical heights = %v, want %v", got, want)
+	}
+
+	// The height is reported after the timeout.
+	reported = au.recheck([]types.VerifyResult{testVerifyResult("3", false, "connection refused")}, 0)
+	if got, want := reportedHeights(reported), []string{"3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reported heights = %v, want %v", got, want)
+	}
+	if got := au.Rechecks(); len(got) != 0 {
+		t.Fatalf("rechecks = %v, want none", got)
+	}
+}
+
+// The restarted audit resumes from the saved audited height and rechecks.
+func TestAuditProgress(t *testing.T) {
+	progressFile := filepath.Join(t.TempDir(), "audit", "contract.json")
+
+	progress, err := loadAuditProgress(progressFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.AuditedHeight != "" {
+		t.Fatalf("audited height = %s, want empty", progress.AuditedHeight)
+	}
+
+	au := newTestAudit()
+	au.setAudited(20)
+	au.recheck([]types.VerifyResult{testVerifyResult("17", true, ""), testVerifyResult("19", false, "timeout")}, time.Hour)
+
+	err = saveAuditProgress(progressFile, au.progress())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progress, err = loadAuditProgress(progressFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestAudit()
+	restored.restore(progress)
+
+	if got := restored.Audited(); got != 20 {
+		t.Fatalf("audited height = %d, want 20", got)
+	}
+	if got, want := restored.Rechecks(), []uint64{17, 19}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rechecks = %v, want %v", got, want)
+	}
+	if !restored.rechecks[17].Equal(au.rechecks[17].Truncate(time.Millisecond)) {
+		t.Fatalf("first check of the height 17 = %v, want %v", restored.rechecks[17], au.rechecks[17])
+	}
+}
@@ -0,0 +1,182 @@
+package gw
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla-anchor/util/fsutil"
+)
+
+// Anchor blocks of the explicit range of heights.
+// Blocks are fetched, aggregated and anchored by the same pipeline as the gateway,
+// but the latest height of the contract is not moved backwards.
+// The progress is saved to the progress file after each batch is confirmed,
+// so the interrupted backfill resumes from the next height of the confirmed height.
+// It stops when the context is canceled, and the context error is returned.
+func StartBackfill(ctx context.Context, a *types.App, contractAddr, blockApi, fromHeight, toHeight, progressFile string) error {
+	util.LogInfo(util.BB("backfill, target anchor contract=") + contractAddr)
+
+	requestPeriod := app.AppFile().Get().Config.Anchor.RequestPeriod
+	if requestPeriod < 0 {
+		return errors.New("request period must be not negative")
+	}
+
+	if app.AppFile().Get().Config.Anchor.CollectBlockCount <= 0 {
+		return errors.New("collect block count must be bigger than 0")
+	}
+
+	from := util.FromStringToUint64(fromHeight)
+	to := util.FromStringToUint64(toHeight)
+	if from == 0 || to < from {
+		return errors.New("end height must be bigger than start height, and start height must be bigger than 0")
+	}
+
+	err := InitBlockAdapter(blockApi)
+	if err != nil {
+		return err
+	}
+
+	progress, err := loadBackfillProgress(progressFile, fromHeight, toHeight)
+	if err != nil {
+		return err
+	}
+
+	confirmed := util.FromStringToUint64(progress.ConfirmedHeight)
+	if confirmed >= to {
+		util.LogInfo(util.BB("backfill is already finished"), util.BB("heights=")+fromHeight+"~"+toHeight)
+		return nil
+	}
+
+	if confirmed >= from {
+		util.LogInfo(util.BB("resume backfill"), util.BB("confirmed height=")+progress.ConfirmedHeight)
+	} else {
+		confirmed = from - 1
+	}
+
+	// Batches include the recorded latest height of the contract instead of lower heights.
+	_, err = initRecordedLatestBlockHeight(a, contractAddr)
+	if err != nil {
+		return err
+	}
+
+	err = initSenders(a, contractAddr)
+	if err != nil {
+		return err
+	}
+
+	state.Mng().SetNextHeight(confirmed + 1)
+	a.Channels.ConfirmedTx = make(chan types.Anchoring, cap(a.Channels.PendingTx))
+
+	// Components are stopped and waited for when it returns, so nothing is broadcasted after it.
+	supervisor := NewSupervisor(ctx)
+	defer supervisor.Stop(nil)
+
+	for i, sender := range a.Senders {
+		supervisor.Go("sender-"+strconv.Itoa(i), SendAnchoringTx(a, sender, ""))
+	}
+	supervisor.Go("confirmer", confirmAnchoringTx(a, nil))
+	supervisor.Go("endpoint checker", func(ctx context.Context) error {
+		checkPrivEndpoints(ctx, blockApi)
+		return nil
+	})
+	supervisor.Go("fetcher", func(ctx context.Context) error {
+		return request(ctx, a, blockApi, to)
+	})
+
+	// Batches of several accounts can be confirmed out of order,
+	// so the confirmed height only moves when the range from the start height is contiguous.
+	total := to - from + 1
+	done := confirmed - from + 1
+	confirmedBatches := make(map[uint64]uint64)
+
+	for {
+		var anchoringTx types.Anchoring
+		select {
+		case anchoringTx = <-a.Channels.ConfirmedTx:
+		case <-supervisor.Done():
+			if err := supervisor.Err(); err != nil {
+				return err
+			}
+			util.LogInfo(util.BB("backfill stopped"), util.BB("confirmed height=")+util.FromUint64ToString(confirmed))
+			return ctx.Err()
+		}
+
+		first := util.FromStringToUint64(anchoringTx.Data[0].Height)
+		latest := util.FromStringToUint64(anchoringTx.Data[len(anchoringTx.Data)-1].Height)
+
+		confirmedBatches[first] = latest
+		done += latest - first + 1
+
+		for {
+			next, ok := confirmedBatches[confirmed+1]
+			if !ok {
+				break
+			}
+			delete(confirmedBatches, confirmed+1)
+			confirmed = next
+		}
+
+		progress.ConfirmedHeight = util.FromUint64ToString(confirmed)
+		err = saveBackfillProgress(progressFile, progress)
+		if err != nil {
+			return err
+		}
+
+		util.LogInfo(
+			util.BB("backfill progress="),
+			util.FromUint64ToString(done)+"/"+util.FromUint64ToString(total),
+			"("+util.FromUint64ToString(done*100/total)+"%)",
+			util.BB("confirmed height=")+progress.ConfirmedHeight,
+		)
+
+		if confirmed >= to {
+			break
+		}
+	}
+
+	util.LogInfo(util.BB("backfill finished"), util.BB("heights=")+fromHeight+"~"+toHeight)
+
+	return nil
+}
+
+// Load the progress of the backfill.
+// A new progress is made if the file does not exist.
+func loadBackfillProgress(progressFile, fromHeight, toHeight string) (types.BackfillProgress, error) {
+	progress := types.NewBackfillProgress(fromHeight, toHeight, "0")
+
+	bytes, err := os.ReadFile(progressFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return progress, nil
+		}
+		return progress, err
+	}
+
+	err = json.Unmarshal(bytes, &progress)
+	if err != nil {
+		return progress, err
+	}
+
+	if progress.FromHeight != fromHeight || progress.ToHeight != toHeight {
+		return progress, errors.New("range of the progress file is different, " + progress.FromHeight + "~" + progress.ToHeight)
+	}
+
+	return progress, nil
+}
+
+// Save the progress of the backfill.
+func saveBackfillProgress(progressFile string, progress types.BackfillProgress) error {
+	bytes, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(progressFile, bytes)
+}
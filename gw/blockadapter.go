@@ -0,0 +1,327 @@
+package gw
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+)
+
+const (
+	blockAdapterAuto       = "auto"
+	blockAdapterLegacy     = "legacy"
+	blockAdapterTendermint = "tendermint"
+	sha256Size             = 32
+)
+
+// The block adapter parses the response of the block API of the private chain.
+// Every adapter normalizes the block header to the same encoding,
+// so block hashes from different APIs can be compared and anchored.
+type BlockAdapter interface {
+	Name() string
+	// Check the response is the format of the adapter.
+	Match(responseBody []byte) bool
+	Parse(responseBody []byte) (types.BlockHeader, error)
+}
+
+var blockAdapters = []BlockAdapter{
+	legacyBlockAdapter{},
+	tendermintBlockAdapter{},
+	evmBlockAdapter{},
+}
+
+var blockAdapterMu sync.Mutex
+var blockAdapterInstance BlockAdapter
+
+// Select the block adapter of the block API.
+// The adapter in the config is used if it is set, otherwise the adapter is detected by probing the latest block.
+// The EVM based chain is requested by the JSON-RPC, so its adapter must be set in the config.
+func InitBlockAdapter(blockApi string) error {
+	name := app.AppFile().Get().Config.PrivateChain.BlockAdapter
+
+	var adapter BlockAdapter
+	if name == "" || name == blockAdapterAuto {
+		responseBody, _, err := PrivEndpointMng().Request(blockApi, latestBlockPath, "")
+		if err != nil {
+			return err
+		}
+
+		adapter, err = detectBlockAdapter(responseBody)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, blockAdapter := range blockAdapters {
+			if blockAdapter.Name() == name {
+				adapter = blockAdapter
+			}
+		}
+
+		if adapter == nil {
+			return errors.New("invalid block adapter " + name)
+		}
+	}
+
+	blockAdapterMu.Lock()
+	blockAdapterInstance = adapter
+	blockAdapterMu.Unlock()
+
+	util.LogInfo(util.BB("block adapter=") + adapter.Name())
+
+	return nil
+}
+
+// Parse the block response by the selected block adapter.
+// If the adapter is not selected yet, it is detected by the response.
+func ParseBlock(responseBody []byte) (types.BlockHeader, error) {
+	blockAdapterMu.Lock()
+	adapter := blockAdapterInstance
+	blockAdapterMu.Unlock()
+
+	if adapter == nil {
+		var err error
+		adapter, err = detectBlockAdapter(responseBody)
+		if err != nil {
+			return types.BlockHeader{}, err
+		}
+	}
+
+	header, err := adapter.Parse(responseBody)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	if header.Hash == "" || header.Height == "" {
+		return types.BlockHeader{}, errors.New("empty block response, check the block API")
+	}
+
+	return header, nil
+}
+
+func detectBlockAdapter(responseBody []byte) (BlockAdapter, error) {
+	for _, adapter := range blockAdapters {
+		if adapter.Match(responseBody) {
+			return adapter, nil
+		}
+	}
+	return nil, errors.New("unknown format of the block response: " + string(responseBody))
+}
+
+// The adapter of the legacy block API such as "/blocks".
+// Hashes and addresses are encoded by hex.
+type legacyBlockAdapter struct{}
+
+func (legacyBlockAdapter) Name() string {
+	return blockAdapterLegacy
+}
+
+func (legacyBlockAdapter) Match(responseBody []byte) bool {
+	var block types.Block
+	if json.Unmarshal(responseBody, &block) != nil {
+		return false
+	}
+	return isHexHash(block.BlockID.Hash)
+}
+
+func (legacyBlockAdapter) Parse(responseBody []byte) (types.BlockHeader, error) {
+	var block types.Block
+	err := json.Unmarshal(responseBody, &block)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	header := block.Block.Header
+	normalized := types.BlockHeader{
+		VersionBlock:        header.Version.Block,
+		VersionApp:          header.Version.App,
+		ChainID:             header.ChainID,
+		Height:              header.Height,
+		Txs:                 block.Block.Data.Txs,
+		LastBlockPartsTotal: header.LastBlockID.Parts.Total,
+	}
+
+	normalized.Time, err = NormalizeTime(header.Time)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	fields := []struct {
+		value string
+		dest  *string
+	}{
+		{block.BlockID.Hash, &normalized.Hash},
+		{header.LastBlockID.Hash, &normalized.LastBlockHash},
+		{header.LastBlockID.Parts.Hash, &normalized.LastBlockPartsHash},
+		{header.LastCommitHash, &normalized.LastCommitHash},
+		{header.DataHash, &normalized.DataHash},
+		{header.ValidatorsHash, &normalized.ValidatorsHash},
+		{header.NextValidatorsHash, &normalized.NextValidatorsHash},
+		{header.ConsensusHash, &normalized.ConsensusHash},
+		{header.AppHash, &normalized.AppHash},
+		{header.LastResultsHash, &normalized.LastResultsHash},
+		{header.EvidenceHash, &normalized.EvidenceHash},
+		{header.ProposerAddress, &normalized.ProposerAddress},
+	}
+
+	for _, field := range fields {
+		*field.dest, err = hexToHex(field.value)
+		if err != nil {
+			return types.BlockHeader{}, err
+		}
+	}
+
+	return normalized, nil
+}
+
+// The adapter of the tendermint service of the cosmos SDK such as "/cosmos/base/tendermint/v1beta1/blocks".
+// Hashes and addresses are encoded by base64, and the proposer address of the sdk block is encoded by bech32.
+type tendermintBlockAdapter struct{}
+
+func (tendermintBlockAdapter) Name() string {
+	return blockAdapterTendermint
+}
+
+func (tendermintBlockAdapter) Match(responseBody []byte) bool {
+	var block types.TendermintBlock
+	if json.Unmarshal(responseBody, &block) != nil {
+		return false
+	}
+
+	hash, err := base64.StdEncoding.DecodeString(block.BlockID.Hash)
+	return err == nil && len(hash) == sha256Size
+}
+
+func (tendermintBlockAdapter) Parse(responseBody []byte) (types.BlockHeader, error) {
+	var block types.TendermintBlock
+	err := json.Unmarshal(responseBody, &block)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	// The block is deprecated by the sdk block in the later version of the cosmos SDK.
+	detail := block.Block
+	if detail.Header.Height == "" {
+		detail = block.SdkBlock
+	}
+
+	header := detail.Header
+	normalized := types.BlockHeader{
+		VersionBlock:        header.Version.Block,
+		VersionApp:          header.Version.App,
+		ChainID:             header.ChainID,
+		Height:              header.Height,
+		Txs:                 detail.Data.Txs,
+		LastBlockPartsTotal: header.LastBlockID.PartSetHeader.Total,
+	}
+
+	normalized.Time, err = NormalizeTime(header.Time)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	fields := []struct {
+		value string
+		dest  *string
+	}{
+		{block.BlockID.Hash, &normalized.Hash},
+		{header.LastBlockID.Hash, &normalized.LastBlockHash},
+		{header.LastBlockID.PartSetHeader.Hash, &normalized.LastBlockPartsHash},
+		{header.LastCommitHash, &normalized.LastCommitHash},
+		{header.DataHash, &normalized.DataHash},
+		{header.ValidatorsHash, &normalized.ValidatorsHash},
+		{header.NextValidatorsHash, &normalized.NextValidatorsHash},
+		{header.ConsensusHash, &normalized.ConsensusHash},
+		{header.AppHash, &normalized.AppHash},
+		{header.LastResultsHash, &normalized.LastResultsHash},
+		{header.EvidenceHash, &normalized.EvidenceHash},
+	}
+
+	for _, field := range fields {
+		*field.dest, err = base64ToHex(field.value)
+		if err != nil {
+			return types.BlockHeader{}, err
+		}
+	}
+
+	normalized.ProposerAddress, err = addressToHex(header.ProposerAddress)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	return normalized, nil
+}
+
+// Normalize the hash which is encoded by hex or base64 to the upper case hex.
+// The recorded hash in the contract is normalized before it is compared with the block of the private chain.
+func NormalizeHash(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	hash = strings.TrimPrefix(hash, evmHexPrefix)
+
+	if isHexHash(hash) {
+		return strings.ToUpper(hash)
+	}
+
+	normalized, err := base64ToHex(hash)
+	if err != nil {
+		return hash
+	}
+	return normalized
+}
+
+// Normalize the timestamp to RFC3339 in UTC.
+func NormalizeTime(timestamp string) (string, error) {
+	if timestamp == "" {
+		return "", nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+func isHexHash(hash string) bool {
+	bytes, err := hex.DecodeString(hash)
+	return err == nil && len(bytes) == sha256Size
+}
+
+func hexToHex(value string) (string, error) {
+	bytes, err := hex.DecodeString(value)
+	if err != nil {
+		return "", errors.New("invalid hex " + value)
+	}
+	return strings.ToUpper(hex.EncodeToString(bytes)), nil
+}
+
+func base64ToHex(value string) (string, error) {
+	bytes, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", errors.New("invalid base64 " + value)
+	}
+	return strings.ToUpper(hex.EncodeToString(bytes)), nil
+}
+
+// The proposer address is encoded by base64 in the block, and by bech32 in the sdk block.
+func addressToHex(address string) (string, error) {
+	if address == "" {
+		return "", nil
+	}
+
+	_, bytes, err := bech32.DecodeAndConvert(address)
+	if err == nil {
+		return strings.ToUpper(hex.EncodeToString(bytes)), nil
+	}
+
+	return base64ToHex(address)
+}
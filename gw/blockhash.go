@@ -0,0 +1,181 @@
+package gw
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+)
+
+// Check the block hash which is reported by the private chain belongs to the header.
+// The hash is recomputed from fields of the header, so the block of the LCD which reports the wrong hash is refused.
+// The block of the EVM based chain is not the tendermint header, so it is not checked.
+func CheckHeaderHash(header types.BlockHeader) error {
+	if !isTendermintHeader(header) {
+		return nil
+	}
+
+	computed, err := ComputeHeaderHash(header)
+	if err != nil {
+		return err
+	}
+
+	if computed != header.Hash {
+		return errors.New("the reported hash of height " + header.Height + " is not matched with the header, reported=" + header.Hash + ", computed=" + computed)
+	}
+
+	return nil
+}
+
+// Compute the tendermint block hash, which is the merkle root of fields of the header.
+// Hashes of the normalized header are the upper case hex, and the time is RFC3339.
+func ComputeHeaderHash(header types.BlockHeader) (string, error) {
+	versionBlock, err := strconv.ParseUint(header.VersionBlock, 10, 64)
+	if err != nil {
+		return "", errors.New("invalid block version: " + err.Error())
+	}
+
+	// The JSON of the tendermint omits the app version 0, so the omitted app version is hashed as 0.
+	// If the block API omits the app version which is not 0, the hash is not matched, and HeaderMismatches reports the app version if other fields are matched.
+	var versionApp uint64
+	if header.VersionApp != "" {
+		versionApp, err = strconv.ParseUint(header.VersionApp, 10, 64)
+		if err != nil {
+			return "", errors.New("invalid app version: " + err.Error())
+		}
+	}
+
+	height, err := strconv.ParseInt(header.Height, 10, 64)
+	if err != nil {
+		return "", errors.New("invalid height: " + err.Error())
+	}
+
+	blockTime, err := time.Parse(time.RFC3339Nano, header.Time)
+	if err != nil {
+		return "", errors.New("invalid time: " + err.Error())
+	}
+
+	tmHeader := tmtypes.Header{
+		Version: tmversion.Consensus{Block: versionBlock, App: versionApp},
+		ChainID: header.ChainID,
+		Height:  height,
+		Time:    blockTime,
+		LastBlockID: tmtypes.BlockID{
+			PartSetHeader: tmtypes.PartSetHeader{Total: uint32(header.LastBlockPartsTotal)},
+		},
+	}
+
+	fields := []struct {
+		name  string
+		value string
+		dest  *[]byte
+	}{
+		{"last block hash", header.LastBlockHash, (*[]byte)(&tmHeader.LastBlockID.Hash)},
+		{"last block parts hash", header.LastBlockPartsHash, (*[]byte)(&tmHeader.LastBlockID.PartSetHeader.Hash)},
+		{"last commit hash", header.LastCommitHash, (*[]byte)(&tmHeader.LastCommitHash)},
+		{"data hash", header.DataHash, (*[]byte)(&tmHeader.DataHash)},
+		{"validators hash", header.ValidatorsHash, (*[]byte)(&tmHeader.ValidatorsHash)},
+		{"next validators hash", header.NextValidatorsHash, (*[]byte)(&tmHeader.NextValidatorsHash)},
+		{"consensus hash", header.ConsensusHash, (*[]byte)(&tmHeader.ConsensusHash)},
+		{"app hash", header.AppHash, (*[]byte)(&tmHeader.AppHash)},
+		{"last results hash", header.LastResultsHash, (*[]byte)(&tmHeader.LastResultsHash)},
+		{"evidence hash", header.EvidenceHash, (*[]byte)(&tmHeader.EvidenceHash)},
+		{"proposer address", header.ProposerAddress, (*[]byte)(&tmHeader.ProposerAddress)},
+	}
+
+	for _, field := range fields {
+		*field.dest, err = hex.DecodeString(field.value)
+		if err != nil {
+			return "", errors.New("invalid " + field.name + ": " + err.Error())
+		}
+	}
+
+	// The header without the validators hash is not hashed by the tendermint.
+	hash := tmHeader.Hash()
+	if hash == nil {
+		return "", errors.New("the header of height " + header.Height + " can not be hashed")
+	}
+
+	return strings.ToUpper(hex.EncodeToString(hash)), nil
+}
+
+// Headers of legacy and tendermint adapters have the block version, but the header of the EVM based chain does not.
+func isTendermintHeader(header types.BlockHeader) bool {
+	return header.VersionBlock != ""
+}
+
+// Check fields of the tendermint header by their own sources, because the recomputed hash which is not matched does not tell the wrong field.
+// The block version is the block protocol of the tendermint, the chain ID is the chain ID of the private chain,
+// the data hash is the hash of transactions of the block, and the last block ID is the hash of the previous block.
+// The empty chain ID, the empty last hash and the block without transactions are not checked.
+func CheckHeaderFields(header types.BlockHeader, chainID, lastHash string) []types.VerifyHeaderField {
+	fields := []types.VerifyHeaderField{
+		types.NewVerifyHeaderField(types.VerifyFieldHeaderVersionBlock, header.VersionBlock, strconv.FormatUint(version.BlockProtocol, 10)),
+	}
+
+	if chainID != "" {
+		fields = append(fields, types.NewVerifyHeaderField(types.VerifyFieldHeaderChainID, header.ChainID, chainID))
+	}
+
+	if len(header.Txs) != 0 {
+		txs := make(tmtypes.Txs, len(header.Txs))
+		for i, tx := range header.Txs {
+			var err error
+			txs[i], err = base64.StdEncoding.DecodeString(tx)
+			if err != nil {
+				txs = nil
+				break
+			}
+		}
+		if txs != nil {
+			fields = append(fields, types.NewVerifyHeaderField(types.VerifyFieldHeaderDataHash, header.DataHash, hexUpper(txs.Hash())))
+		}
+	}
+
+	if lastHash != "" {
+		fields = append(fields, types.NewVerifyHeaderField(types.VerifyFieldHeaderLastBlockID, header.LastBlockHash, lastHash))
+	}
+
+	return fields
+}
+
+// Get fields of the tendermint header which cause the reported hash not to be matched with the recomputed hash.
+// Fields which are not matched with their own sources are reported. If all of them are matched and the app version is omitted,
+// the app version is reported, because it is hashed as 0. Otherwise the wrong field does not have its own source such as the app hash,
+// and the header is reported.
+func HeaderMismatches(header types.BlockHeader, chainID, lastHash string) []string {
+	if !isTendermintHeader(header) {
+		return nil
+	}
+
+	computed, err := ComputeHeaderHash(header)
+	if err != nil {
+		return []string{types.VerifyFieldHeader}
+	}
+	if computed == header.Hash {
+		return nil
+	}
+
+	var mismatches []string
+	for _, field := range CheckHeaderFields(header, chainID, lastHash) {
+		if !field.Matched {
+			mismatches = append(mismatches, field.Field)
+		}
+	}
+
+	switch {
+	case len(mismatches) != 0:
+		return mismatches
+	case header.VersionApp == "":
+		return []string{types.VerifyFieldHeaderVersionApp}
+	default:
+		return []string{types.VerifyFieldHeader}
+	}
+}
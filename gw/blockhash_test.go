@@ -0,0 +1,409 @@
+package gw
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+var updateBlockFixtures = flag.Bool("update", false, "generate block fixtures of testdata again")
+
+const (
+	blockFixtureChainID    = "private-fixture-1"
+	blockFixtureConsPrefix = "xplavalcons"
+)
+
+// Block responses of the private chain in testdata.
+// They are generated by encoders of the tendermint and the cosmos SDK with -update,
+// so the hash of the block ID is computed by the tendermint, not by the gateway.
+var blockFixtures = []struct {
+	file    string
+	adapter string
+	height  string
+}{
+	{"legacy_block_1.json", blockAdapterLegacy, "1"},
+	{"legacy_block_2.json", blockAdapterLegacy, "2"},
+	{"tendermint_block_1.json", blockAdapterTendermint, "1"},
+	{"tendermint_block_2.json", blockAdapterTendermint, "2"},
+	{"sdk_block_1.json", blockAdapterTendermint, "1"},
+	{"sdk_block_2.json", blockAdapterTendermint, "2"},
+}
+
+// Make blocks of heights 1 and 2 which are committed by one validator.
+// The block of height 1 has the empty last block ID and the empty last commit.
+func newFixtureBlocks(t *testing.T) ([]*tmtypes.Block, []tmtypes.BlockID, []byte) {
+	t.Helper()
+
+	privKey := ed25519.GenPrivKeyFromSecret([]byte("fixture validator"))
+	validator := tmtypes.NewValidator(privKey.PubKey(), 10)
+	validators := tmtypes.NewValidatorSet([]*tmtypes.Validator{validator})
+
+	var blocks []*tmtypes.Block
+	var blockIDs []tmtypes.BlockID
+
+	lastCommit := &tmtypes.Commit{}
+	var lastBlockID tmtypes.BlockID
+	blockTime := time.Date(2023, 3, 14, 5, 6, 7, 123456789, time.UTC)
+
+	for height := int64(1); height <= 2; height++ {
+		txs := []tmtypes.Tx{tmtypes.Tx("fixture-tx-1"), tmtypes.Tx("fixture-tx-2")}
+		block := tmtypes.MakeBlock(height, txs, lastCommit, nil)
+
+		block.Version = tmversion.Consensus{Block: 11, App: 1}
+		block.ChainID = blockFixtureChainID
+		block.Time = blockTime.Add(time.Second * time.Duration(height))
+		block.LastBlockID = lastBlockID
+		block.ValidatorsHash = validators.Hash()
+		block.NextValidatorsHash = validators.Hash()
+		block.ConsensusHash = tmtypes.HashConsensusParams(*tmtypes.DefaultConsensusParams())
+		block.AppHash = bytes.Repeat([]byte{byte(height)}, 32)
+		block.LastResultsHash = tmtypes.NewResults(nil).Hash()
+		block.ProposerAddress = validator.Address
+
+		partSet := block.MakePartSet(tmtypes.BlockPartSizeBytes)
+		blockID := tmtypes.BlockID{Hash: block.Hash(), PartSetHeader: partSet.Header()}
+
+		// The commit of this block is the last commit of the next block.
+		vote := &tmtypes.Vote{
+			Type:             tmproto.PrecommitType,
+			Height:           height,
+			BlockID:          blockID,
+			Timestamp:        block.Time.Add(time.Millisecond * 500),
+			ValidatorAddress: validator.Address,
+		}
+		signature, err := privKey.Sign(tmtypes.VoteSignBytes(blockFixtureChainID, vote.ToProto()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		vote.Signature = signature
+		lastCommit = tmtypes.NewCommit(height, 0, blockID, []tmtypes.CommitSig{vote.CommitSig()})
+
+		blocks = append(blocks, block)
+		blockIDs = append(blockIDs, blockID)
+		lastBlockID = blockID
+	}
+
+	return blocks, blockIDs, validator.Address
+}
+
+// Write responses of the legacy block API, the tendermint service, and the tendermint service of the later cosmos SDK.
+// The later cosmos SDK responds the sdk block which has the bech32 proposer address.
+func writeBlockFixtures(t *testing.T) {
+	t.Helper()
+
+	blocks, blockIDs, proposer := newFixtureBlocks(t)
+
+	consAddr, err := bech32.ConvertAndEncode(blockFixtureConsPrefix, proposer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, block := range blocks {
+		legacy, err := tmjson.MarshalIndent(coretypes.ResultBlock{BlockID: blockIDs[i], Block: block}, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		protoBlockID := blockIDs[i].ToProto()
+		protoBlock, err := block.ToProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tendermint, err := codec.ProtoMarshalJSON(&tmservice.GetBlockByHeightResponse{BlockId: &protoBlockID, Block: protoBlock}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var indented bytes.Buffer
+		err = json.Indent(&indented, tendermint, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var response map[string]interface{}
+		err = json.Unmarshal(tendermint, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sdkBlock := response["block"].(map[string]interface{})
+		sdkBlock["header"].(map[string]interface{})["proposer_address"] = consAddr
+		delete(response, "block")
+		response["sdk_block"] = sdkBlock
+
+		sdk, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		height := strconv.FormatInt(block.Height, 10)
+		for prefix, fixture := range map[string][]byte{
+			"legacy_block_":     legacy,
+			"tendermint_block_": indented.Bytes(),
+			"sdk_block_":        sdk,
+		} {
+			err = os.WriteFile(filepath.Join("testdata", prefix+height+".json"), append(fixture, '\n'), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func readBlockFixture(t *testing.T, file string) ([]byte, BlockAdapter) {
+	t.Helper()
+
+	responseBody, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adapter, err := detectBlockAdapter(responseBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return responseBody, adapter
+}
+
+// The hash of the block ID which the response reports.
+func fixtureBlockIDHash(t *testing.T, responseBody []byte) string {
+	t.Helper()
+
+	var response struct {
+		BlockID struct {
+			Hash string `json:"hash"`
+		} `json:"block_id"`
+	}
+	err := json.Unmarshal(responseBody, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NormalizeHash(response.BlockID.Hash)
+}
+
+// The hash which is computed from the header of each format is the hash of the block ID.
+func TestComputeHeaderHash(t *testing.T) {
+	if *updateBlockFixtures {
+		writeBlockFixtures(t)
+	}
+
+	for _, fixture := range blockFixtures {
+		t.Run(fixture.file, func(t *testing.T) {
+			responseBody, adapter := readBlockFixture(t, fixture.file)
+			if adapter.Name() != fixture.adapter {
+				t.Fatalf("adapter = %s, want %s", adapter.Name(), fixture.adapter)
+			}
+
+			header, err := adapter.Parse(responseBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header.Height != fixture.height || header.ChainID != blockFixtureChainID {
+				t.Fatalf("height = %s, chain ID = %s", header.Height, header.ChainID)
+			}
+
+			// The block of height 1 does not have the last block.
+			if fixture.height == "1" && (header.LastBlockHash != "" || header.LastBlockPartsHash != "" || header.LastBlockPartsTotal != 0) {
+				t.Fatalf("last block of height 1 = %s, %d, %s", header.LastBlockHash, header.LastBlockPartsTotal, header.LastBlockPartsHash)
+			}
+
+			computed, err := ComputeHeaderHash(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := fixtureBlockIDHash(t, responseBody)
+			if computed != want || header.Hash != want {
+				t.Fatalf("computed hash = %s, reported hash = %s, want %s", computed, header.Hash, want)
+			}
+
+			err = CheckHeaderHash(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Formats of the same block are normalized to the same header.
+func TestBlockFormatsNormalized(t *testing.T) {
+	for _, height := range []string{"1", "2"} {
+		var headers []types.BlockHeader
+		for _, prefix := range []string{"legacy_block_", "tendermint_block_", "sdk_block_"} {
+			responseBody, adapter := readBlockFixture(t, prefix+height+".json")
+			header, err := adapter.Parse(responseBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers = append(headers, header)
+		}
+
+		for _, header := range headers[1:] {
+			if header.Hash != headers[0].Hash || header.ProposerAddress != headers[0].ProposerAddress || header.Time != headers[0].Time {
+				t.Fatalf("header of height %s = %+v, want %+v", height, header, headers[0])
+			}
+		}
+	}
+}
+
+// The header of which any field is changed is refused.
+func TestCheckHeaderHashTampered(t *testing.T) {
+	responseBody, adapter := readBlockFixture(t, "legacy_block_2.json")
+	original, err := adapter.Parse(responseBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherHash := strings.Repeat("AB", sha256Size)
+
+	testCases := []struct {
+		name   string
+		tamper func(header *types.BlockHeader)
+	}{
+		{"hash", func(header *types.BlockHeader) { header.Hash = otherHash }},
+		{"block version", func(header *types.BlockHeader) { header.VersionBlock = "10" }},
+		{"app version", func(header *types.BlockHeader) { header.VersionApp = "2" }},
+		{"chain ID", func(header *types.BlockHeader) { header.ChainID = "private-fixture-2" }},
+		{"height", func(header *types.BlockHeader) { header.Height = "3" }},
+		{"time", func(header *types.BlockHeader) { header.Time = "2023-03-14T05:06:09.123456788Z" }},
+		{"last block hash", func(header *types.BlockHeader) { header.LastBlockHash = otherHash }},
+		{"last block parts total", func(header *types.BlockHeader) { header.LastBlockPartsTotal = 2 }},
+		{"last block parts hash", func(header *types.BlockHeader) { header.LastBlockPartsHash = otherHash }},
+		{"last commit hash", func(header *types.BlockHeader) { header.LastCommitHash = otherHash }},
+		{"data hash", func(header *types.BlockHeader) { header.DataHash = otherHash }},
+		{"validators hash", func(header *types.BlockHeader) { header.ValidatorsHash = otherHash }},
+		{"next validators hash", func(header *types.BlockHeader) { header.NextValidatorsHash = otherHash }},
+		{"consensus hash", func(header *types.BlockHeader) { header.ConsensusHash = otherHash }},
+		{"app hash", func(header *types.BlockHeader) { header.AppHash = otherHash }},
+		{"last results hash", func(header *types.BlockHeader) { header.LastResultsHash = otherHash }},
+		{"evidence hash", func(header *types.BlockHeader) { header.EvidenceHash = otherHash }},
+		{"proposer address", func(header *types.BlockHeader) { header.ProposerAddress = strings.Repeat("CD", 20) }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := original
+			tc.tamper(&header)
+
+			if CheckHeaderHash(header) == nil {
+				t.Fatal("the tampered header is not refused")
+			}
+		})
+	}
+
+	// The response of which the field is changed is refused.
+	for _, fixture := range blockFixtures {
+		t.Run("response "+fixture.file, func(t *testing.T) {
+			responseBody, adapter := readBlockFixture(t, fixture.file)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(responseBody, &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			block, ok := response["block"].(map[string]interface{})
+			if !ok {
+				block = response["sdk_block"].(map[string]interface{})
+			}
+			block["header"].(map[string]interface{})["chain_id"] = "private-fixture-2"
+
+			tampered, err := json.Marshal(response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			header, err := adapter.Parse(tampered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if CheckHeaderHash(header) == nil {
+				t.Fatal("the tampered response is not refused")
+			}
+		})
+	}
+}
+
+// The field of the header which is changed is reported by its own source, and the header is reported if the field has no source.
+func TestHeaderMismatches(t *testing.T) {
+	var headers []types.BlockHeader
+	for _, file := range []string{"legacy_block_1.json", "legacy_block_2.json"} {
+		responseBody, adapter := readBlockFixture(t, file)
+		header, err := adapter.Parse(responseBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, header)
+	}
+	original, lastHash := headers[1], headers[0].Hash
+	recorded := types.NewData(original.Height, original.Hash, original.DataHash, original.Time)
+
+	otherHash := strings.Repeat("AB", sha256Size)
+
+	testCases := []struct {
+		name   string
+		tamper func(header *types.BlockHeader)
+		want   []string
+	}{
+		{"nothing", func(header *types.BlockHeader) {}, nil},
+		{"reported hash", func(header *types.BlockHeader) { header.Hash = otherHash }, []string{types.VerifyFieldHash}},
+		{"block version", func(header *types.BlockHeader) { header.VersionBlock = "10" }, []string{types.VerifyFieldHeaderVersionBlock}},
+		{"omitted app version", func(header *types.BlockHeader) { header.VersionApp = "" }, []string{types.VerifyFieldHeaderVersionApp}},
+		{"chain ID", func(header *types.BlockHeader) { header.ChainID = "private-fixture-2" }, []string{types.VerifyFieldHeaderChainID}},
+		{"last block hash", func(header *types.BlockHeader) { header.LastBlockHash = otherHash }, []string{types.VerifyFieldHeaderLastBlockID}},
+		{"data hash", func(header *types.BlockHeader) { header.DataHash = otherHash }, []string{types.VerifyFieldHeaderDataHash, types.VerifyFieldMerkle}},
+		{"app hash", func(header *types.BlockHeader) { header.AppHash = otherHash }, []string{types.VerifyFieldHeader}},
+		{"chain ID and app hash", func(header *types.BlockHeader) {
+			header.ChainID = "private-fixture-2"
+			header.AppHash = otherHash
+		}, []string{types.VerifyFieldHeaderChainID}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := original
+			header.Txs = append([]string{}, original.Txs...)
+			tc.tamper(&header)
+
+			mismatches := CompareBlock(header, blockFixtureChainID, lastHash, recorded)
+			if strings.Join(mismatches, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("mismatches = %v, want %v", mismatches, tc.want)
+			}
+		})
+	}
+
+	// The app version 0 is omitted by the JSON of the tendermint, so the header of which the app version is 0 is hashed without it.
+	header := original
+	header.VersionApp = "0"
+	computed, err := ComputeHeaderHash(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.VersionApp = ""
+	omitted, err := ComputeHeaderHash(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if computed != omitted {
+		t.Fatalf("hash of the omitted app version = %s, want %s", omitted, computed)
+	}
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	auditTable = "auditResult"
+)
+
+// Connect to DB for results of the audit.
+func AuditDbInit() (*sql.DB, error) {
+	return tableDbInit(auditTable)
+}
+
+// Save the inconsistent result of the audit.
+func SaveAuditResult(db *sql.DB, height, kind, result, detail string) error {
+	_, err := db.Exec(
+		"insert into "+auditTable+" (height, kind, result, detail, timestamp) values (?, ?, ?, ?, ?)",
+		height, kind, result, detail, time.Now(),
+	)
+	return err
+}
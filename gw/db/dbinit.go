@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
 	_ "github.com/go-sql-driver/mysql"
@@ -114,7 +115,7 @@ func logIndexInit(db *sql.DB) {
 	var errIndex string
 	var infoIndex string
 
-	util.InitLogIndex().UseDb()
+	state.Mng().UseDb()
 
 	queryErrResult, _ := db.Query("select index_id from errLog order by cast(index_id as signed) desc limit 1")
 	defer queryErrResult.Close()
@@ -123,12 +124,6 @@ func logIndexInit(db *sql.DB) {
 		queryErrResult.Scan(&errIndex)
 	}
 
-	if errIndex == "" {
-		util.InitLogIndex().NewErrLogIndex("0")
-	} else {
-		util.InitLogIndex().NewErrLogIndex(errIndex)
-	}
-
 	queryInfoResult, _ := db.Query("select index_id from infoLog order by cast(index_id as signed) desc limit 1")
 	defer queryInfoResult.Close()
 
@@ -136,9 +131,6 @@ func logIndexInit(db *sql.DB) {
 		queryInfoResult.Scan(&infoIndex)
 	}
 
-	if infoIndex == "" {
-		util.InitLogIndex().NewInfoLogIndex("0")
-	} else {
-		util.InitLogIndex().NewInfoLogIndex(infoIndex)
-	}
+	// The next index is bigger than the last index.
+	state.Mng().SetLogIndex(util.FromStringToUint64(infoIndex)+1, util.FromStringToUint64(errIndex)+1)
 }
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+)
+
+const (
+	leaseTable = "leaderLease"
+)
+
+// Connect to DB for the leader lease.
+// The lease uses its own connection, so logs are not saved to DB unless the log type is DB.
+func LeaseDbInit() (*sql.DB, error) {
+	return tableDbInit(leaseTable)
+}
+
+// Connect to DB, and create the table if it does not exist.
+// Every connection of the pool uses the DB.
+func tableDbInit(table string) (*sql.DB, error) {
+	dbConf := app.AppFile().Get().Config.Anchor.DB
+	dataSource := dbConf.DBUserName + ":" + dbConf.DBPassword + "@tcp(" + dbConf.DBHost + ":" + dbConf.DBPort + ")/"
+
+	rootDb, err := sql.Open("mysql", dataSource)
+	if err != nil {
+		return nil, err
+	}
+	defer rootDb.Close()
+
+	isDbExist, err := dbExist(rootDb, dbConf.DBName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDbExist {
+		runCreateSql(rootDb, "db")
+	}
+
+	db, err := sql.Open("mysql", dataSource+dbConf.DBName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	err = runCreateSql(db, table+"_table")
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Acquire the lease if it is expired, or renew the lease if the owner holds it.
+// The time of DB is used, so clocks of instances do not need to be synchronized.
+// Columns are updated from left to right, so the expiration is extended only when the owner holds the lease.
+func TryAcquireLease(db *sql.DB, name, owner string, duration time.Duration) (bool, error) {
+	_, err := db.Exec(
+		"insert into "+leaseTable+" (name, owner, expires_at) values (?, ?, date_add(now(3), interval ? microsecond)) "+
+			"on duplicate key update "+
+			"owner = if(owner = values(owner) or expires_at < now(3), values(owner), owner), "+
+			"expires_at = if(owner = values(owner), values(expires_at), expires_at)",
+		name, owner, duration.Microseconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	var holder string
+	err = db.QueryRow("select owner from "+leaseTable+" where name = ?", name).Scan(&holder)
+	if err != nil {
+		return false, err
+	}
+
+	return holder == owner, nil
+}
+
+// Release the lease, so the standby takes over without waiting for the expiration.
+func ReleaseLease(db *sql.DB, name, owner string) error {
+	_, err := db.Exec("delete from "+leaseTable+" where name = ? and owner = ?", name, owner)
+	return err
+}
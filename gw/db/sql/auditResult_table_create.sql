@@ -0,0 +1,8 @@
+-- create table
+CREATE TABLE IF NOT EXISTS auditResult (
+    height varchar(100) NOT NULL,
+    kind varchar(100) NOT NULL,
+    result varchar(100) NOT NULL,
+    detail varchar(1000) NOT NULL,
+    timestamp datetime NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- create table
+CREATE TABLE IF NOT EXISTS leaderLease (
+    name varchar(255) NOT NULL,
+    owner varchar(255) NOT NULL,
+    expires_at datetime(3) NOT NULL,
+    PRIMARY KEY (name)
+);
\ No newline at end of file
@@ -0,0 +1,187 @@
+package gw
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+)
+
+// Run the gateway without broadcasting.
+// Fetch blocks of the private chain, aggregate them and build the anchoring message,
+// then simulate gas and sign the transaction offline.
+// Each batch is written as a JSON line to stdout or the output file.
+// If the range of heights is not set, start from the next height of the recorded latest block,
+// and run until the gateway is interrupted.
+func StartDryRun(a *types.App, contractAddr, blockApi, fromHeight, toHeight, outFile string) error {
+	util.LogInfo(util.BB("dry run, target anchor contract=") + contractAddr)
+
+	requestPeriod := app.AppFile().Get().Config.Anchor.RequestPeriod
+	if requestPeriod < 0 {
+		return errors.New("request period must be not negative")
+	}
+
+	if app.AppFile().Get().Config.Anchor.CollectBlockCount <= 0 {
+		return errors.New("collect block count must be bigger than 0")
+	}
+
+	err := InitBlockAdapter(blockApi)
+	if err != nil {
+		return err
+	}
+
+	if fromHeight == "" {
+		err := initLatestBlockHeight(a, contractAddr)
+		if err != nil {
+			return err
+		}
+	} else {
+		state.Mng().SetNextHeight(util.FromStringToUint64(fromHeight))
+	}
+
+	endHeight := util.FromStringToUint64(toHeight)
+	if endHeight != 0 && endHeight < state.Mng().NextHeight() {
+		return errors.New("end height must be bigger than start height")
+	}
+
+	var out io.Writer = os.Stdout
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	// The dry run signs transactions with the first account.
+	sender := a.Senders[0]
+	err = syncSequence(sender)
+	if err != nil {
+		return err
+	}
+	xplac := cloneClient(sender)
+
+	// The fetcher and the endpoint checker stop when the dry run returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go checkPrivEndpoints(ctx, blockApi)
+	requestErr := make(chan error, 1)
+	go func() {
+		requestErr <- request(ctx, a, blockApi, endHeight)
+		close(a.Channels.AnchringTx)
+	}()
+
+	for anchoringTx := range a.Channels.AnchringTx {
+		results, err := simulateAnchoringTx(xplac, contractAddr, anchoringTx)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			line, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+
+			_, err = fmt.Fprintln(out, string(line))
+			if err != nil {
+				return err
+			}
+
+			util.LogInfo(util.BB("dry run"), util.BB("heights=")+result.FirstHeight+"~"+result.LatestHeight, util.BB("gas=")+util.FromUint64ToString(result.Gas), util.BB("fee=")+result.Fee)
+		}
+	}
+
+	err = <-requestErr
+	if err != nil {
+		return err
+	}
+
+	util.LogInfo(util.BB("dry run finished"))
+
+	return nil
+}
+
+// Simulate gas and sign the anchoring transaction without broadcasting.
+// Simulation checks the sequence of the account, so every batch is signed with the sequence on the chain.
+// The batch which exceeds the max tx bytes or the max tx gas is split in the same way as the gateway,
+// so each half is the result of its own transaction.
+func simulateAnchoringTx(xplac *client.XplaClient, contractAddr string, anchoringTx types.Anchoring) ([]types.DryRunResult, error) {
+	pubConf := app.AppFile().Get().Config.PublicChain
+	xplac.WithGasAdjustment(pubConf.GasAdj).WithGasLimit(pubConf.GasLimit)
+
+	user, err := senderAddress(xplac)
+	if err != nil {
+		return nil, err
+	}
+
+	execMsg, err := anchoringMsg(anchoringTx)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit, size, max, ok := exceededTxLimit(len(execMsg), 0); ok && len(anchoringTx.Data) > 1 {
+		return simulateSplitAnchoringTx(xplac, contractAddr, anchoringTx, limit, size, max)
+	}
+
+	seq := util.FromUint64ToString(state.Mng().Sequence(user))
+
+	var txbytes []byte
+	err = withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		txbytes, err = signAnchoringTx(xplac, contractAddr, seq, execMsg)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := decodeTxFee(txbytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit, size, max, ok := exceededTxLimit(len(txbytes), fee.GasLimit); ok {
+		if len(anchoringTx.Data) > 1 {
+			return simulateSplitAnchoringTx(xplac, contractAddr, anchoringTx, limit, size, max)
+		}
+		warnSingleBlockLimit(anchoringTx, len(txbytes), fee.GasLimit)
+	}
+
+	// The commitment of the memo sink is not JSON.
+	if IsMemoSink() {
+		result := types.NewDryRunResult(anchoringTx, "", fee.GasLimit, fee.Amount.String(), len(txbytes))
+		result.Memo = execMsg
+		return []types.DryRunResult{result}, nil
+	}
+
+	return []types.DryRunResult{types.NewDryRunResult(anchoringTx, execMsg, fee.GasLimit, fee.Amount.String(), len(txbytes))}, nil
+}
+
+// Split the batch in half, and simulate each.
+func simulateSplitAnchoringTx(xplac *client.XplaClient, contractAddr string, anchoringTx types.Anchoring, limit string, size, max int) ([]types.DryRunResult, error) {
+	first, second := splitAnchoring(anchoringTx)
+	logSplitAnchoring(first, second, limit, size, max)
+
+	firstResults, err := simulateAnchoringTx(xplac, contractAddr, first)
+	if err != nil {
+		return nil, err
+	}
+
+	secondResults, err := simulateAnchoringTx(xplac, contractAddr, second)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(firstResults, secondResults...), nil
+}
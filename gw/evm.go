@@ -0,0 +1,237 @@
+package gw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const (
+	blockAdapterEvm         = "evm"
+	evmMethodBlockNumber    = "eth_blockNumber"
+	evmMethodBlockByNumber  = "eth_getBlockByNumber"
+	evmJsonRpcVersion       = "2.0"
+	evmHexPrefix            = "0x"
+	evmNotFinalizedResponse = `{"error":"` + requestBiggerHeightErr + `"}`
+)
+
+type evmRequest struct {
+	JsonRpc string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type evmResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// The block of the EVM based chain such as geth and besu.
+type evmBlock struct {
+	Number           string `json:"number"`
+	Hash             string `json:"hash"`
+	ParentHash       string `json:"parentHash"`
+	StateRoot        string `json:"stateRoot"`
+	TransactionsRoot string `json:"transactionsRoot"`
+	ReceiptsRoot     string `json:"receiptsRoot"`
+	Miner            string `json:"miner"`
+	Timestamp        string `json:"timestamp"`
+}
+
+// The private chain is the EVM based chain, and blocks are requested by the Ethereum JSON-RPC.
+func isEvmSource() bool {
+	return app.AppFile().Get().Config.PrivateChain.BlockAdapter == blockAdapterEvm
+}
+
+// Request the block of the height to the JSON-RPC endpoint.
+// The block is only responded after it is deeper than the finality depth from the head,
+// otherwise it is responded as the block which is not created yet, so the aggregator waits for it.
+func doEvmRequestTo(rpcUrl, blockHeight string) ([]byte, error) {
+	util.LogInfo(util.BB("URL=")+rpcUrl, util.BB("height=")+blockHeight)
+
+	depth := app.AppFile().Get().Config.PrivateChain.FinalityDepth
+	if depth < 0 {
+		depth = 0
+	}
+
+	result, err := evmCall(rpcUrl, evmMethodBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var head string
+	err = json.Unmarshal(result, &head)
+	if err != nil {
+		return nil, err
+	}
+
+	headNumber, err := fromEvmQuantity(head)
+	if err != nil {
+		return nil, err
+	}
+
+	if headNumber < uint64(depth) {
+		return []byte(evmNotFinalizedResponse), nil
+	}
+	finalized := headNumber - uint64(depth)
+
+	height := finalized
+	if blockHeight != latestBlockPath {
+		height = util.FromStringToUint64(blockHeight)
+		if height > finalized {
+			return []byte(evmNotFinalizedResponse), nil
+		}
+	}
+
+	result, err = evmCall(rpcUrl, evmMethodBlockByNumber, toEvmQuantity(height), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(result) == "null" {
+		return []byte(evmNotFinalizedResponse), nil
+	}
+
+	return result, nil
+}
+
+// Call the method of the JSON-RPC and return the result.
+func evmCall(rpcUrl, method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	requestBody, err := json.Marshal(evmRequest{
+		JsonRpc: evmJsonRpcVersion,
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", rpcUrl, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	httpClient, err := PrivHttpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+		return nil, NewRequestStatusError(response.StatusCode, parseRetryAfter(response.Header.Get("Retry-After")), string(responseBody))
+	}
+
+	var rpcResponse evmResponse
+	err = json.Unmarshal(responseBody, &rpcResponse)
+	if err != nil {
+		return nil, errors.New("invalid JSON-RPC response: " + string(responseBody))
+	}
+
+	if rpcResponse.Error != nil {
+		return nil, errors.New(method + " failed: " + rpcResponse.Error.Message)
+	}
+
+	return rpcResponse.Result, nil
+}
+
+// The adapter of the block of the Ethereum JSON-RPC.
+// The transactions root is anchored as the merkle root of the block.
+type evmBlockAdapter struct{}
+
+func (evmBlockAdapter) Name() string {
+	return blockAdapterEvm
+}
+
+func (evmBlockAdapter) Match(responseBody []byte) bool {
+	var block evmBlock
+	if json.Unmarshal(responseBody, &block) != nil {
+		return false
+	}
+	return strings.HasPrefix(block.Hash, evmHexPrefix) && block.TransactionsRoot != ""
+}
+
+func (evmBlockAdapter) Parse(responseBody []byte) (types.BlockHeader, error) {
+	var block evmBlock
+	err := json.Unmarshal(responseBody, &block)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+
+	var normalized types.BlockHeader
+
+	number, err := fromEvmQuantity(block.Number)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+	normalized.Height = util.FromUint64ToString(number)
+
+	timestamp, err := fromEvmQuantity(block.Timestamp)
+	if err != nil {
+		return types.BlockHeader{}, err
+	}
+	normalized.Time = time.Unix(int64(timestamp), 0).UTC().Format(time.RFC3339Nano)
+
+	fields := []struct {
+		value string
+		dest  *string
+	}{
+		{block.Hash, &normalized.Hash},
+		{block.ParentHash, &normalized.LastBlockHash},
+		{block.TransactionsRoot, &normalized.DataHash},
+		{block.StateRoot, &normalized.AppHash},
+		{block.ReceiptsRoot, &normalized.LastResultsHash},
+		{block.Miner, &normalized.ProposerAddress},
+	}
+
+	for _, field := range fields {
+		*field.dest, err = hexToHex(strings.TrimPrefix(field.value, evmHexPrefix))
+		if err != nil {
+			return types.BlockHeader{}, err
+		}
+	}
+
+	return normalized, nil
+}
+
+func fromEvmQuantity(quantity string) (uint64, error) {
+	number, ok := new(big.Int).SetString(strings.TrimPrefix(quantity, evmHexPrefix), 16)
+	if !ok || !number.IsUint64() {
+		return 0, errors.New("invalid quantity " + quantity)
+	}
+	return number.Uint64(), nil
+}
+
+func toEvmQuantity(number uint64) string {
+	return evmHexPrefix + new(big.Int).SetUint64(number).Text(16)
+}
@@ -0,0 +1,358 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+)
+
+const (
+	invalidBlockHeightErr      = "invalid block height"
+	DefaultGapProbeConcurrency = 8
+	gapProbeBatchSize          = 500
+	defaultGapCheckPeriod      = 3600000
+	defaultGapCheckWindow      = 1000
+)
+
+// Find heights which are not recorded in the contract.
+// The range is probed by the block data query of the contract in batches,
+// and heights in a batch are probed in parallel.
+func FindGaps(a *types.App, contractAddr string, fromHeight, toHeight uint64, concurrency int) ([]uint64, error) {
+	if fromHeight == 0 || toHeight < fromHeight {
+		return nil, errors.New("end height must be bigger than start height, and start height must be bigger than 0")
+	}
+
+	// The memo sink has anchored heights in the local index.
+	if IsMemoSink() {
+		return MemoIndexMng().Gaps(fromHeight, toHeight), nil
+	}
+
+	if concurrency <= 0 {
+		concurrency = DefaultGapProbeConcurrency
+	}
+
+	var gaps []uint64
+	for batchFrom := fromHeight; batchFrom <= toHeight; batchFrom += gapProbeBatchSize {
+		batchTo := batchFrom + gapProbeBatchSize - 1
+		if batchTo > toHeight {
+			batchTo = toHeight
+		}
+
+		batchGaps, err := probeGaps(a, contractAddr, batchFrom, batchTo, concurrency)
+		if err != nil {
+			return nil, err
+		}
+		gaps = append(gaps, batchGaps...)
+
+		util.LogInfo(
+			util.BB("probed heights=")+util.FromUint64ToString(fromHeight)+"~"+util.FromUint64ToString(batchTo),
+			util.BB("gaps=")+util.ToString(len(gaps), "0"),
+		)
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+
+	return gaps, nil
+}
+
+// Probe heights of the batch by workers.
+// Each worker owns the copy of the XPLA client.
+func probeGaps(a *types.App, contractAddr string, fromHeight, toHeight uint64, concurrency int) ([]uint64, error) {
+	heights := make(chan uint64)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var gaps []uint64
+	var probeErr error
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			xplac := cloneClient(a.PubClient)
+			for height := range heights {
+				recorded, err := probeBlockData(xplac, contractAddr, height)
+
+				mu.Lock()
+				if err != nil && probeErr == nil {
+					probeErr = err
+				}
+				if err == nil && !recorded {
+					gaps = append(gaps, height)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for height := fromHeight; height <= toHeight; height++ {
+		heights <- height
+	}
+	close(heights)
+	wg.Wait()
+
+	return gaps, probeErr
+}
+
+// Check the block data of the height is recorded in the contract.
+func probeBlockData(xplac *client.XplaClient, contractAddr string, height uint64) (bool, error) {
+	queryMsg := xtypes.QueryMsg{
+		ContractAddress: contractAddr,
+		QueryMsg:        `{"block_data":{"height":"` + util.FromUint64ToString(height) + `"}}`,
+	}
+
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		_, err := xplac.QueryContract(queryMsg).Query()
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), invalidBlockHeightErr) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Anchor the missing heights by the accounts of the gateway.
+// Gaps are not contiguous, so they are aggregated in the separated batches from the gateway.
+// Return the number of batches.
+// It stops when the context is done, and batches which are not queued yet are found again by the next check.
+func RepairGaps(ctx context.Context, a *types.App, contractAddr, blockApi string, gaps []uint64) (int, error) {
+	if len(gaps) == 0 {
+		return 0, nil
+	}
+
+	latestHeight, err := queryLatestBlockHeight(cloneClient(a.PubClient), contractAddr)
+	if err != nil {
+		return 0, err
+	}
+
+	count := app.AppFile().Get().Config.Anchor.CollectBlockCount
+	if count <= 0 {
+		return 0, errors.New("collect block count must be bigger than 0")
+	}
+
+	var batches []types.Anchoring
+	var data []types.Data
+	for i, height := range gaps {
+		newData, err := fetchBlockData(ctx, a, blockApi, util.FromUint64ToString(height))
+		if err != nil {
+			return 0, err
+		}
+		data = append(data, newData)
+
+		if len(data) == count || i == len(gaps)-1 {
+			// The latest height of the contract is not moved backwards.
+			latest := newData.Height
+			if util.FromStringToUint64(latestHeight) > height {
+				latest = latestHeight
+			}
+
+			batches = append(batches, types.NewAncoring(data, latest))
+			data = nil
+		}
+	}
+
+	for i, batch := range batches {
+		util.LogInfo(util.BB("repair gaps"), util.BB("heights=")+batch.Data[0].Height+"~"+batch.Data[len(batch.Data)-1].Height)
+		select {
+		case a.Channels.AnchringTx <- batch:
+		case <-ctx.Done():
+			return i, ctx.Err()
+		}
+	}
+
+	return len(batches), nil
+}
+
+// Anchor gaps out of the gateway.
+// The pipeline of the gateway is started to send batches, and it returns after all batches are confirmed.
+func StartRepairGaps(a *types.App, contractAddr, blockApi string, gaps []uint64) error {
+	if len(gaps) == 0 {
+		return nil
+	}
+
+	err := InitBlockAdapter(blockApi)
+	if err != nil {
+		return err
+	}
+
+	err = initSenders(a, contractAddr)
+	if err != nil {
+		return err
+	}
+
+	a.Channels.ConfirmedTx = make(chan types.Anchoring, cap(a.Channels.PendingTx))
+
+	// Components are stopped and waited for when it returns, so nothing is broadcasted after it.
+	supervisor := NewSupervisor(context.Background())
+	defer supervisor.Stop(nil)
+
+	for i, sender := range a.Senders {
+		supervisor.Go("sender-"+strconv.Itoa(i), SendAnchoringTx(a, sender, ""))
+	}
+	supervisor.Go("confirmer", confirmAnchoringTx(a, nil))
+
+	count := app.AppFile().Get().Config.Anchor.CollectBlockCount
+	if count <= 0 {
+		return errors.New("collect block count must be bigger than 0")
+	}
+	total := (len(gaps) + count - 1) / count
+
+	repairErr := make(chan error, 1)
+	supervisor.wg.Add(1)
+	go func() {
+		defer supervisor.wg.Done()
+		_, err := RepairGaps(supervisor.ctx, a, contractAddr, blockApi, gaps)
+		repairErr <- err
+	}()
+
+	for confirmed := 0; confirmed < total; {
+		select {
+		case err := <-repairErr:
+			if err != nil {
+				return err
+			}
+		case <-supervisor.Done():
+			return supervisor.Err()
+		case <-a.Channels.ConfirmedTx:
+			confirmed++
+			util.LogInfo(util.BB("repaired batches=") + util.ToString(confirmed, "0") + "/" + util.ToString(total, "0"))
+		}
+	}
+
+	util.LogInfo(util.BB("repair gaps finished"))
+
+	return nil
+}
+
+// Check gaps of the recent heights periodically in the gateway, and anchor them.
+// Batches in flight can be confirmed after batches of higher heights,
+// so heights which are able to be in flight are excluded from the window.
+// Settings are read in every check, so they can be changed by reloading the config.
+func checkGaps(ctx context.Context, a *types.App, contractAddr, blockApi string) {
+	xplac := cloneClient(a.PubClient)
+
+	for {
+		anchorConf := app.AppFile().Get().Config.Anchor
+
+		// The negative period disables checking gaps.
+		period := anchorConf.GapCheckPeriod
+		if period < 0 {
+			if !sleepContext(ctx, time.Millisecond*time.Duration(defaultGapCheckPeriod)) {
+				return
+			}
+			continue
+		}
+		if period == 0 {
+			period = defaultGapCheckPeriod
+		}
+
+		window := anchorConf.GapCheckWindow
+		if window <= 0 {
+			window = defaultGapCheckWindow
+		}
+
+		if !sleepContext(ctx, time.Millisecond*time.Duration(period)) {
+			return
+		}
+
+		// Gaps are repaired after the gateway is resumed.
+		if !PauseMng().Wait(ctx) {
+			return
+		}
+
+		latestHeight, err := QueryRecordedLatestHeight(xplac, contractAddr)
+		if err != nil {
+			util.LogWarning("failed to query the latest block height for checking gaps:", err)
+			continue
+		}
+
+		inFlight := anchorConf.CollectBlockCount * (cap(a.Channels.PendingTx) + cap(a.Channels.RetryTx) + len(a.Senders))
+		latest := util.FromStringToUint64(latestHeight)
+		if latest <= uint64(inFlight) {
+			continue
+		}
+
+		toHeight := latest - uint64(inFlight)
+		fromHeight := uint64(1)
+		if toHeight > uint64(window) {
+			fromHeight = toHeight - uint64(window) + 1
+		}
+
+		gaps, err := FindGaps(a, contractAddr, fromHeight, toHeight, DefaultGapProbeConcurrency)
+		if err != nil {
+			util.LogWarning("failed to check gaps:", err)
+			continue
+		}
+
+		if len(gaps) == 0 {
+			continue
+		}
+
+		util.LogWarning("found gaps in the contract, heights=" + strings.Join(GapRanges(gaps), ","))
+
+		_, err = RepairGaps(ctx, a, contractAddr, blockApi, gaps)
+		if err != nil && ctx.Err() == nil {
+			util.LogWarning("failed to repair gaps:", err)
+		}
+	}
+}
+
+// Get the block info of the height from the private chain.
+func fetchBlockData(ctx context.Context, a *types.App, blockApi, height string) (types.Data, error) {
+	responseBody, err := requestBlock(ctx, a, blockApi, height)
+	if err != nil {
+		return types.Data{}, err
+	}
+
+	header, err := ParseBlock(responseBody)
+	if err != nil {
+		return types.Data{}, err
+	}
+
+	if header.Height != height {
+		return types.Data{}, errors.New("invalid block response of height " + height)
+	}
+
+	err = CheckHeaderHash(header)
+	if err != nil {
+		return types.Data{}, err
+	}
+
+	return types.NewData(height, header.Hash, header.DataHash, header.Time), nil
+}
+
+// Convert sorted heights to ranges such as "10~20".
+func GapRanges(gaps []uint64) []string {
+	var ranges []string
+
+	for i := 0; i < len(gaps); {
+		j := i
+		for j+1 < len(gaps) && gaps[j+1] == gaps[j]+1 {
+			j++
+		}
+
+		if i == j {
+			ranges = append(ranges, util.FromUint64ToString(gaps[i]))
+		} else {
+			ranges = append(ranges, util.FromUint64ToString(gaps[i])+"~"+util.FromUint64ToString(gaps[j]))
+		}
+		i = j + 1
+	}
+
+	return ranges
+}
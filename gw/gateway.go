@@ -1,16 +1,19 @@
 package gw
 
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/Moonyongjung/xpla-anchor/util"
 	"github.com/Moonyongjung/xpla.go/client"
-	"github.com/Moonyongjung/xpla.go/key"
 	xtypes "github.com/Moonyongjung/xpla.go/types"
 	"github.com/mitchellh/mapstructure"
 )
@@ -18,31 +21,99 @@ import (
 // Start the gateway of the anchor.
 // Request the block info to the private chain periodically,
 // and send the transaction as anchoring message to the main chain.
-func StartGW(a *types.App, contractAddr, blockApi, log string) {
+// Fetching blocks, broadcasting and confirming transactions run as a pipeline,
+// so the fetcher keeps collecting blocks while anchoring transactions are in flight.
+// Each component is restarted by the supervisor when it fails,
+// and the gateway returns the error after the supervisor stops by too many restarts.
+func StartGW(ctx context.Context, a *types.App, contractAddr, blockApi, log string) error {
 	util.LogInfo(util.BB("target anchor contract=") + contractAddr)
 
 	// Set requested period in the config.yaml (milliseconds)
 	requestPeriod := app.AppFile().Get().Config.Anchor.RequestPeriod
 	if requestPeriod < 0 {
-		util.LogErr(types.ErrGw, "request period must be not negative")
+		return errors.New("request period must be not negative")
 	}
 
-	go request(a, blockApi, requestPeriod)
+	if app.AppFile().Get().Config.Anchor.CollectBlockCount <= 0 {
+		return errors.New("collect block count must be bigger than 0")
+	}
+
+	err := InitBlockAdapter(blockApi)
+	if err != nil {
+		return err
+	}
+
+	err = initGW(ctx, a, contractAddr)
+	if err != nil {
+		return err
+	}
 
-	initGW(a, contractAddr)
+	supervisor := NewSupervisor(ctx)
+	for i, sender := range a.Senders {
+		supervisor.Go("sender-"+strconv.Itoa(i), SendAnchoringTx(a, sender, log))
+	}
+	supervisor.Go("confirmer", confirmAnchoringTx(a, restorePendingTxs()))
+	supervisor.Go("account reporter", func(ctx context.Context) error {
+		reportAccounts(ctx, a)
+		return nil
+	})
+	supervisor.Go("gap checker", func(ctx context.Context) error {
+		checkGaps(ctx, a, contractAddr, blockApi)
+		return nil
+	})
+	supervisor.Go("endpoint checker", func(ctx context.Context) error {
+		checkPrivEndpoints(ctx, blockApi)
+		return nil
+	})
+	supervisor.Go("maintenance checker", func(ctx context.Context) error {
+		checkMaintenance(ctx)
+		return nil
+	})
+	supervisor.Go("fetcher", func(ctx context.Context) error {
+		return request(ctx, a, blockApi, 0)
+	})
+
+	<-supervisor.Done()
+
+	// Every component returns before the gateway returns, so nothing is broadcasted after it.
+	supervisor.Stop(nil)
+
+	return supervisor.Err()
 }
 
-// Set the periodic time.
-func request(a *types.App, blockApi string, requestPeriod int) {
+// Request blocks in order through the rate limiter.
+// If the end height is set, stop requesting after the block of the end height is collected.
+// The next height is kept in the state, so the fetcher continues from it after it is restarted.
+// Blocks which are collected but not sent are requested again by the restarted fetcher.
+// It returns nil when the context is done.
+func request(ctx context.Context, a *types.App, blockApi string, endHeight uint64) error {
+	var ag aggregator
+	defer ag.rewind()
+
 	for {
-		if <-a.Channels.HttpClientStartSignal {
-			_, err := DoRequest(a, blockApi, "", true)
-			if err != nil {
-				util.LogErr(types.ErrGw, err)
-				panic(err)
+		if !PauseMng().Wait(ctx) {
+			return nil
+		}
+
+		height := state.Mng().NextHeight()
+		if endHeight != 0 && height > endHeight {
+			ag.flush(ctx, a)
+			return nil
+		}
+
+		responseBody, err := requestBlock(ctx, a, blockApi, util.FromUint64ToString(height))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
 			}
-			BlockListMng().IncreaseLatestBlockHeight()
-			time.Sleep(time.Millisecond * time.Duration(requestPeriod))
+			return err
+		}
+
+		if ag.aggregate(ctx, a, responseBody) {
+			state.Mng().IncreaseNextHeight()
+		} else if ctx.Err() != nil {
+			// The waiting of the aggregation is interrupted by the context.
+			return nil
 		}
 	}
 }
@@ -51,58 +122,215 @@ func request(a *types.App, blockApi string, requestPeriod int) {
 // At first, query the recorded latest block height to the anchor contract
 // in order to request next block to the private chain.
 // If the that block height is zero, the gateway request the genesis block info of the private chain.
-func initGW(a *types.App, contractAddr string) {
+func initGW(ctx context.Context, a *types.App, contractAddr string) error {
+	// The latest height of the memo sink is decided by confirmed entries of the memo index.
+	if IsMemoSink() {
+		err := resolvePendingMemoIndex(ctx, a.PubClient)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := initLatestBlockHeight(a, contractAddr)
+	if err != nil {
+		return err
+	}
+
+	return initSenders(a, contractAddr)
+}
+
+// Check the accounts which send anchoring transactions are authorized by the contract,
+// and query the sequence number of each account.
+func initSenders(a *types.App, contractAddr string) error {
+	authzConf := app.AppFile().Get().Config.Anchor.Authz
+	granter := authzConf.Granter
+
+	if granter != "" && authzConf.FeeGranter != "" {
+		return errors.New("the fee granter is for submitters, set the fee grant in the authz mode")
+	}
+
+	// In the memo sink, each account sends the memo by the transaction to itself.
+	var submitters map[string]bool
+	if IsMemoSink() {
+		if granter != "" {
+			return errors.New("the authz mode is not supported by the memo sink")
+		}
+		if authzConf.FeeGranter != "" {
+			return errors.New("the fee granter is not supported by the memo sink")
+		}
+		util.LogInfo(util.BB("memo sink, chain ID=") + app.AppFile().Get().Config.PrivateChain.ChainID)
+	} else {
+		// The contract before supporting submitters has not the config query.
+		var err error
+		submitters, err = querySubmitters(a.PubClient, contractAddr)
+		if err != nil {
+			util.LogWarning("failed to query submitters of the anchor contract:", err)
+
+			// The contract before supporting submitters records the latest height of the last included message.
+			if len(a.Senders) > 1 {
+				util.LogWarning("the anchor contract may not keep the latest height, transactions of several accounts may move it backwards. migrate the contract or use one account")
+			}
+		}
+	}
+
+	// In the authz mode, the granter sends anchoring messages through the grantees.
+	if granter != "" {
+		util.LogInfo(util.BB("authz mode, granter=") + granter)
+
+		if submitters != nil && !submitters[granter] {
+			util.LogWarning(granter, "is not the owner or a submitter of the anchor contract")
+		}
+	}
+
+	// Query the sequence number of each account in order to run the gateway.
+	for _, sender := range a.Senders {
+		user, err := senderAddress(sender)
+		if err != nil {
+			return err
+		}
+
+		if granter == "" && submitters != nil && !submitters[user] {
+			util.LogWarning(user, "is not the owner or a submitter of the anchor contract")
+		}
+
+		err = syncSequence(sender)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Set the block height to request next by the recorded latest block height in the contract.
+func initLatestBlockHeight(a *types.App, contractAddr string) error {
+	latestHeight, err := initRecordedLatestBlockHeight(a, contractAddr)
+	if err != nil {
+		return err
+	}
+
+	if latestHeight == "0" {
+		state.Mng().SetNextHeight(util.FromStringToUint64(types.GenesisBlockNum))
+	} else {
+		state.Mng().SetNextHeight(util.FromStringToUint64(latestHeight) + 1)
+	}
+
+	return nil
+}
+
+// Check the recorded latest block height in the contract.
+// Anchoring messages do not set the latest height lower than it.
+func initRecordedLatestBlockHeight(a *types.App, contractAddr string) (string, error) {
+	latestHeight, err := QueryRecordedLatestHeight(a.PubClient, contractAddr)
+	if err != nil {
+		return "", err
+	}
+
+	util.LogInfo(util.BB("recorded latest block height=") + latestHeight)
+	state.Mng().SetRecordedLatest(util.FromStringToUint64(latestHeight))
+
+	return latestHeight, nil
+}
+
+// Query the recorded latest block height in the contract.
+func queryLatestBlockHeight(xplac *client.XplaClient, contractAddr string) (string, error) {
 	queryMsg := xtypes.QueryMsg{
 		ContractAddress: contractAddr,
 		QueryMsg:        types.QueryLatestBlockMsg,
 	}
 
-	// Check the recorded latest block height in the contract.
-	res, err := a.PubClient.QueryContract(queryMsg).Query()
+	var res string
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		res, err = xplac.QueryContract(queryMsg).Query()
+		return err
+	})
 	if err != nil {
-		util.LogErr(types.ErrGw, err)
-		panic(err)
+		return "", err
 	}
 
 	var latestBlock types.QueryLatestBlockResponse
 	responseData := util.JsonUnmarshalData(&latestBlock, []byte(res))
 	mapstructure.Decode(responseData, &latestBlock)
 
-	util.LogInfo(util.BB("recorded latest block height=") + latestBlock.Data.LatestHeight)
+	return latestBlock.Data.LatestHeight, nil
+}
+
+// Query the recorded block info of the height in the contract.
+func queryBlockData(xplac *client.XplaClient, contractAddr, height string) (types.Data, error) {
+	var data types.Data
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		data, err = queryBlockDataFrom(xplac, contractAddr, height)
+		return err
+	})
 
-	if latestBlock.Data.LatestHeight == "0" {
-		BlockListMng().NewLatestBlockHeight(types.GenesisBlockNum)
-	} else {
-		BlockListMng().NewLatestBlockHeight(latestBlock.Data.LatestHeight)
-		BlockListMng().IncreaseLatestBlockHeight()
+	return data, err
+}
+
+// Query the recorded block info to the current LCD of the XPLA client.
+func queryBlockDataFrom(xplac *client.XplaClient, contractAddr, height string) (types.Data, error) {
+	queryMsg := xtypes.QueryMsg{
+		ContractAddress: contractAddr,
+		QueryMsg:        `{"block_data":{"height":"` + height + `"}}`,
 	}
 
-	user, err := key.Bech32AddrString(a.PubClient.GetPrivateKey())
+	res, err := xplac.QueryContract(queryMsg).Query()
 	if err != nil {
-		util.LogErr(types.ErrGw, err)
-		panic(err)
+		return types.Data{}, err
+	}
+
+	var blockInfo types.QueryBlockInfoResponse
+	responseData := util.JsonUnmarshalData(&blockInfo, []byte(res))
+	mapstructure.Decode(responseData, &blockInfo)
+
+	return types.NewData(blockInfo.Data.Height, blockInfo.Data.BlockHash, blockInfo.Data.DataMerkle, blockInfo.Data.Timestamp), nil
+}
+
+// Query the accounts which are able to send anchoring transactions.
+func querySubmitters(xplac *client.XplaClient, contractAddr string) (map[string]bool, error) {
+	queryMsg := xtypes.QueryMsg{
+		ContractAddress: contractAddr,
+		QueryMsg:        types.QueryConfigMsg,
 	}
 
-	// Query the sequence number of the account in order to run the gateway.
-	seqRes, err := querySequence(a.PubClient, user)
+	var res string
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		res, err = xplac.QueryContract(queryMsg).Query()
+		return err
+	})
 	if err != nil {
-		util.LogErr(types.ErrGw, err)
-		panic(err)
+		return nil, err
 	}
 
-	seq := util.ParsingQueryAccount(seqRes)
-	SequenceMng().NewSequence(seq)
+	var config types.QueryConfigResponse
+	responseData := util.JsonUnmarshalData(&config, []byte(res))
+	mapstructure.Decode(responseData, &config)
 
-	a.Channels.HttpClientStartSignal <- true
+	submitters := make(map[string]bool)
+	submitters[config.Data.Owner] = true
+	for _, submitter := range config.Data.Submitters {
+		submitters[submitter] = true
+	}
+
+	return submitters, nil
 }
 
 // Request block info.
-func DoRequest(a *types.App, blockApi string, blockHeight string, isGateway bool) ([]byte, error) {
-	if isGateway {
-		blockHeight = BlockListMng().NowLatestBlockHeight()
+// Healthy endpoints of the private chain are requested in order, and the failed endpoint is ejected.
+func DoRequest(a *types.App, blockApi string, blockHeight string) ([]byte, error) {
+	responseBody, _, err := PrivEndpointMng().Request(blockApi, blockHeight, "")
+	return responseBody, err
+}
+
+// Request block info to the endpoint.
+func doRequestTo(privLcd string, blockApi string, blockHeight string) ([]byte, error) {
+	if isEvmSource() {
+		return doEvmRequestTo(privLcd, blockHeight)
 	}
 
-	privLcdUrl := app.AppFile().Get().Config.PrivateChain.LCD + blockApi + "/" + blockHeight
+	privLcdUrl := privLcd + blockApi + "/" + blockHeight
 	util.LogInfo(util.BB("URL=") + privLcdUrl)
 
 	request, err := http.NewRequest("GET", privLcdUrl, nil)
@@ -110,22 +338,11 @@ func DoRequest(a *types.App, blockApi string, blockHeight string, isGateway bool
 		return nil, err
 	}
 
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
+	httpClient, err := PrivHttpClient()
+	if err != nil {
+		return nil, err
 	}
 
-	httpClient.Timeout = time.Second * 30
-
-	defer func() {
-		if err := recover(); err != nil {
-			util.LogInfo(err)
-		}
-	}()
-
 	response, err := httpClient.Do(request)
 	if err != nil {
 		return nil, err
@@ -137,19 +354,63 @@ func DoRequest(a *types.App, blockApi string, blockHeight string, isGateway bool
 		return nil, err
 	}
 
-	if isGateway {
-		go aggregate(a, responseBody)
+	// The legacy block API responds the error of the height which is not created yet by 500,
+	// so it is not regarded as overloaded.
+	if response.StatusCode == http.StatusTooManyRequests ||
+		(response.StatusCode >= http.StatusInternalServerError && !strings.Contains(string(responseBody), requestBiggerHeightErr)) {
+		return nil, NewRequestStatusError(response.StatusCode, parseRetryAfter(response.Header.Get("Retry-After")), string(responseBody))
 	}
 
 	return responseBody, nil
 }
 
+// Request block info through the rate limiter.
+// If the private chain is overloaded, wait by the backoff and request again.
+func requestBlock(ctx context.Context, a *types.App, blockApi string, blockHeight string) ([]byte, error) {
+	for {
+		if !RateLimitMng().Wait(ctx) {
+			return nil, ctx.Err()
+		}
+
+		responseBody, privLcd, err := PrivEndpointMng().Request(blockApi, blockHeight, "")
+		if err != nil {
+			var statusErr *RequestStatusError
+			if errors.As(err, &statusErr) {
+				RateLimitMng().Throttle(statusErr.RetryAfter)
+				continue
+			}
+			return nil, err
+		}
+
+		RateLimitMng().Recover()
+
+		// The block which is not created yet is handled by the aggregator.
+		if PrivEndpointMng().RequireAgreement() && !strings.Contains(string(responseBody), requestBiggerHeightErr) {
+			err = PrivEndpointMng().CheckAgreement(blockApi, blockHeight, privLcd, responseBody)
+			if err != nil {
+				util.LogWarning("block hash is not agreed, request again:", err)
+				if !sleepContext(ctx, time.Millisecond*time.Duration(waitingBlockTime)) {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+		}
+
+		return responseBody, nil
+	}
+}
+
 // check the sequence number of the anchor account.
 func querySequence(xplac *client.XplaClient, addr string) (string, error) {
 	queryAccAddressMsg := xtypes.QueryAccAddressMsg{
 		Address: addr,
 	}
-	res, err := xplac.AccAddress(queryAccAddressMsg).Query()
+	var res string
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		res, err = xplac.AccAddress(queryAccAddressMsg).Query()
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,279 @@
+package gw
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/gw/db"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla-anchor/util/fsutil"
+)
+
+const (
+	haBackendMysql       = "mysql"
+	haBackendFile        = "file"
+	defaultLeaseDuration = 15000
+	defaultRenewPeriod   = 5000
+	fileLeaseSettleTime  = 500
+	leaseNamePrefix      = "anchor-"
+)
+
+// The lease of the leader.
+// Only the instance which holds the lease fetches blocks and sends anchoring transactions.
+type Lease interface {
+	TryAcquire(owner string, duration time.Duration) (bool, error)
+	Release(owner string) error
+}
+
+var haMu sync.Mutex
+var haLease Lease
+var haOwner string
+
+// Run the gateway as the active/passive high availability.
+// The standby waits until the lease of the leader expires, and then it starts the gateway.
+// The leader renews the lease periodically. If the lease is lost or is not able to be renewed before the expiration,
+// the leader stops the gateway and waits until its components return before it returns,
+// because another instance may start anchoring with the same accounts.
+// It returns nil if the context is done.
+func StartHA(ctx context.Context, a *types.App, contractAddr, blockApi, log string) error {
+	haConf := app.AppFile().Get().Config.Anchor.HA
+
+	duration := haConf.LeaseDuration
+	if duration <= 0 {
+		duration = defaultLeaseDuration
+	}
+	leaseDuration := time.Millisecond * time.Duration(duration)
+
+	period := haConf.RenewPeriod
+	if period <= 0 {
+		period = defaultRenewPeriod
+	}
+	renewPeriod := time.Millisecond * time.Duration(period)
+
+	if renewPeriod >= leaseDuration {
+		return util.LogErr(types.ErrHA, "renew period must be shorter than lease duration")
+	}
+
+	lease, err := newLease(haConf, contractAddr)
+	if err != nil {
+		return util.LogErr(types.ErrHA, err)
+	}
+
+	owner := haConf.InstanceID
+	if owner == "" {
+		hostname, _ := os.Hostname()
+		owner = hostname + "-" + strconv.Itoa(os.Getpid())
+	}
+
+	haMu.Lock()
+	haLease = lease
+	haOwner = owner
+	haMu.Unlock()
+
+	util.LogInfo(util.BB("high availability mode, instance="), owner, util.BB("backend=")+haConf.Backend)
+
+	for {
+		acquired, err := lease.TryAcquire(owner, leaseDuration)
+		if err != nil {
+			util.LogWarning("failed to acquire the leader lease:", err)
+		}
+
+		if acquired {
+			break
+		}
+
+		util.LogWait("standby, the leader lease is held by another instance...")
+		if !sleepContext(ctx, renewPeriod) {
+			return nil
+		}
+	}
+
+	util.LogInfo(util.BB("became the leader"), owner)
+	gwCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	gwErr := make(chan error, 1)
+	go func() {
+		gwErr <- StartGW(gwCtx, a, contractAddr, blockApi, log)
+	}()
+
+	// Stop the gateway and wait until it returns, so nothing is broadcasted after the lease is given up.
+	stopGW := func(err error) error {
+		cancel()
+		<-gwErr
+		return err
+	}
+
+	lastRenew := time.Now()
+	for {
+		select {
+		case err := <-gwErr:
+			return err
+		case <-time.After(renewPeriod):
+		}
+
+		acquired, err := lease.TryAcquire(owner, leaseDuration)
+		if err != nil {
+			util.LogWarning("failed to renew the leader lease:", err)
+
+			// The lease can expire before the next renewal.
+			if time.Since(lastRenew)+renewPeriod >= leaseDuration {
+				return stopGW(util.LogErr(types.ErrHA, "the leader lease is not renewed until the expiration"))
+			}
+			continue
+		}
+
+		if !acquired {
+			return stopGW(util.LogErr(types.ErrHA, "the leader lease is lost"))
+		}
+
+		lastRenew = time.Now()
+	}
+}
+
+// Release the leader lease when the gateway stops.
+func StopHA() {
+	haMu.Lock()
+	defer haMu.Unlock()
+
+	if haLease == nil {
+		return
+	}
+
+	err := haLease.Release(haOwner)
+	if err != nil {
+		util.LogWarning("failed to release the leader lease:", err)
+		return
+	}
+
+	util.LogInfo(util.BB("leader lease is released"))
+}
+
+func newLease(haConf app.HA, contractAddr string) (Lease, error) {
+	switch haConf.Backend {
+	case haBackendMysql:
+		leaseDb, err := db.LeaseDbInit()
+		if err != nil {
+			return nil, err
+		}
+
+		return &mysqlLease{
+			db:   leaseDb,
+			name: leaseNamePrefix + contractAddr,
+		}, nil
+
+	case haBackendFile:
+		if haConf.LockFile == "" {
+			return nil, errors.New("lock file path is mandatory for the file backend")
+		}
+
+		return &fileLease{
+			path: haConf.LockFile,
+		}, nil
+
+	default:
+		return nil, errors.New("invalid backend of the high availability " + haConf.Backend)
+	}
+}
+
+// The lease is recorded in the table of the configured MySQL database.
+type mysqlLease struct {
+	db   *sql.DB
+	name string
+}
+
+func (l *mysqlLease) TryAcquire(owner string, duration time.Duration) (bool, error) {
+	return db.TryAcquireLease(l.db, l.name, owner, duration)
+}
+
+func (l *mysqlLease) Release(owner string) error {
+	return db.ReleaseLease(l.db, l.name, owner)
+}
+
+// The lease is recorded in the lock file on the shared storage.
+// The expiration is compared with the local time, so clocks of instances must be synchronized.
+type fileLease struct {
+	path string
+}
+
+type fileLeaseRecord struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (l *fileLease) TryAcquire(owner string, duration time.Duration) (bool, error) {
+	record, err := l.read()
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	now := time.Now()
+	if err == nil && record.Owner != owner && now.UnixMilli() < record.ExpiresAt {
+		return false, nil
+	}
+
+	err = l.write(fileLeaseRecord{Owner: owner, ExpiresAt: now.Add(duration).UnixMilli()})
+	if err != nil {
+		return false, err
+	}
+
+	// Standby instances can write the lock file at the same time after the expiration,
+	// so the last writer is the leader.
+	if record.Owner != owner {
+		time.Sleep(time.Millisecond * time.Duration(fileLeaseSettleTime))
+	}
+
+	record, err = l.read()
+	if err != nil {
+		return false, err
+	}
+
+	return record.Owner == owner, nil
+}
+
+func (l *fileLease) Release(owner string) error {
+	record, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	if record.Owner != owner {
+		return nil
+	}
+
+	return os.Remove(l.path)
+}
+
+func (l *fileLease) read() (fileLeaseRecord, error) {
+	var record fileLeaseRecord
+
+	bytes, err := os.ReadFile(l.path)
+	if err != nil {
+		return record, err
+	}
+
+	err = json.Unmarshal(bytes, &record)
+	if err != nil {
+		return record, err
+	}
+
+	return record, nil
+}
+
+// The record replaces the lock file at once.
+func (l *fileLease) write(record fileLeaseRecord) error {
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(l.path, bytes)
+}
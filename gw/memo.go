@@ -0,0 +1,446 @@
+package gw
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+	"github.com/mitchellh/mapstructure"
+	"github.com/tendermint/tendermint/crypto/merkle"
+)
+
+const (
+	sinkContract         = "contract"
+	sinkMemo             = "memo"
+	memoCommitmentPrefix = "anchor"
+	defaultMemoAmount    = "1axpla"
+)
+
+// The XPLA client sets the memo of the transaction by the global variable,
+// so memo transactions are signed one by one.
+var memoSignMu sync.Mutex
+
+// Check the sink of anchoring is valid.
+func CheckSink() error {
+	sink := app.AppFile().Get().Config.Anchor.Sink
+	if sink != "" && sink != sinkContract && sink != sinkMemo {
+		return errors.New("invalid sink " + sink)
+	}
+	return nil
+}
+
+// Batches are anchored by memos of transactions instead of the anchor contract.
+func IsMemoSink() bool {
+	return app.AppFile().Get().Config.Anchor.Sink == sinkMemo
+}
+
+// Make the commitment of the batch which is written to the memo.
+// e.g. anchor:privatechain-1:101-110:[merkle root of the batch]
+func MemoCommitment(chainID string, data []types.Data) (string, string, error) {
+	if len(data) == 0 {
+		return "", "", errors.New("empty batch")
+	}
+
+	root, err := BatchRoot(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	commitment := strings.Join([]string{
+		memoCommitmentPrefix,
+		chainID,
+		data[0].Height + "-" + data[len(data)-1].Height,
+		root,
+	}, ":")
+
+	return commitment, root, nil
+}
+
+// Get the merkle root of blocks in the batch.
+// Each leaf is the compact JSON of the block info.
+func BatchRoot(data []types.Data) (string, error) {
+	leaves := make([][]byte, len(data))
+	for i, blockData := range data {
+		bytes, err := json.Marshal(blockData)
+		if err != nil {
+			return "", err
+		}
+		leaves[i] = bytes
+	}
+
+	return strings.ToUpper(hex.EncodeToString(merkle.HashFromByteSlices(leaves))), nil
+}
+
+// Sign the bank self-send transaction which has the commitment as the memo.
+func signMemoTx(xplac *client.XplaClient, seq, memo string) ([]byte, error) {
+	user, err := senderAddress(xplac)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := app.AppFile().Get().Config.Anchor.Memo.Amount
+	if amount == "" {
+		amount = defaultMemoAmount
+	}
+
+	memoSignMu.Lock()
+	defer memoSignMu.Unlock()
+
+	xtypes.Memo = memo
+	defer func() { xtypes.Memo = "" }()
+
+	return xplac.WithSequence(seq).BankSend(xtypes.BankSendMsg{
+		FromAddress: user,
+		ToAddress:   user,
+		Amount:      amount,
+	}).CreateAndSignTx()
+}
+
+// Record the batch to the memo index by the status of its transaction.
+func recordMemoIndex(status string, anchoring types.Anchoring, txHash string) error {
+	chainID := app.AppFile().Get().Config.PrivateChain.ChainID
+
+	commitment, root, err := MemoCommitment(chainID, anchoring.Data)
+	if err != nil {
+		return err
+	}
+
+	return MemoIndexMng().Append(types.NewMemoIndexEntry(status, chainID, root, commitment, txHash, anchoring.Data))
+}
+
+// Resolve pending entries of the memo index which are left by the previous run.
+// The transaction of the pending entry may be included after the gateway stopped, so it is queried until the confirm timeout.
+// Entries are queried at the same time, so the start is delayed by the confirm timeout at most regardless of the number of entries.
+// The entry of the transaction which has the commitment is confirmed, otherwise it is failed and the batch is anchored again.
+func resolvePendingMemoIndex(ctx context.Context, xplac *client.XplaClient) error {
+	timeout := app.AppFile().Get().Config.Anchor.TxConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultTxConfirmTimeout
+	}
+
+	pending := MemoIndexMng().Pending()
+	statuses := make([]string, len(pending))
+
+	var wg sync.WaitGroup
+	for i, entry := range pending {
+		wg.Add(1)
+		go func(i int, entry types.MemoIndexEntry) {
+			defer wg.Done()
+			statuses[i] = pendingMemoStatus(ctx, cloneClient(xplac), entry, timeout)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	for i, entry := range pending {
+		entry.Status = statuses[i]
+		err := MemoIndexMng().Append(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the status of the pending entry by its transaction.
+func pendingMemoStatus(ctx context.Context, xplac *client.XplaClient, entry types.MemoIndexEntry, timeout int) string {
+	txRes, err := waitTx(ctx, xplac, entry.TxHash, timeout)
+	switch {
+	case ctx.Err() != nil:
+		return types.MemoIndexPending
+	case err != nil:
+		util.LogWarning("pending memo tx is not found, anchor the batch again.", util.BB("txhash=")+entry.TxHash, err)
+		return types.MemoIndexFailed
+	case txRes.TxResponse.Code != 0 || txRes.Tx.Body.Memo != entry.Commitment:
+		util.LogWarning("pending memo tx is failed, anchor the batch again.", util.BB("txhash=")+entry.TxHash)
+		return types.MemoIndexFailed
+	}
+
+	util.LogInfo(util.BB("pending memo tx is confirmed"), util.BB("heights=")+entry.FromHeight+"~"+entry.ToHeight, util.BB("txhash=")+entry.TxHash)
+	return types.MemoIndexConfirmed
+}
+
+// Check the transaction of the entry has the commitment of blocks in the entry.
+func VerifyMemo(xplac *client.XplaClient, entry types.MemoIndexEntry) error {
+	commitment, _, err := MemoCommitment(entry.ChainID, entry.Data)
+	if err != nil {
+		return err
+	}
+
+	if commitment != entry.Commitment {
+		return errors.New("the commitment of the index is not matched with its blocks")
+	}
+
+	var res string
+	err = withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		res, err = xplac.Tx(xtypes.QueryTxMsg{Value: entry.TxHash}).Query()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var txRes types.QueryTxResponse
+	responseData := util.JsonUnmarshalData(&txRes, []byte(res))
+	mapstructure.Decode(responseData, &txRes)
+
+	if txRes.TxResponse.Code != 0 {
+		return errors.New("the anchoring tx is failed, txhash=" + entry.TxHash)
+	}
+
+	if txRes.Tx.Body.Memo != commitment {
+		return errors.New("the memo of the anchoring tx is not matched, memo=" + txRes.Tx.Body.Memo + ", commitment=" + commitment)
+	}
+
+	return nil
+}
+
+// Get the recorded latest block height from the contract, or from the memo index in the memo sink.
+func QueryRecordedLatestHeight(xplac *client.XplaClient, contractAddr string) (string, error) {
+	if IsMemoSink() {
+		return util.FromUint64ToString(MemoIndexMng().Latest()), nil
+	}
+	return queryLatestBlockHeight(xplac, contractAddr)
+}
+
+// Get the recorded block info of the height.
+// In the memo sink, the block info is found in the memo index, and the commitment is checked by the transaction of the public chain.
+// If the height is not recorded, the error wraps ErrNotRecorded.
+func QueryRecordedBlockData(xplac *client.XplaClient, contractAddr, height string) (types.Data, error) {
+	if !IsMemoSink() {
+		data, err := queryBlockData(xplac, contractAddr, height)
+		if err != nil && strings.Contains(err.Error(), invalidBlockHeightErr) {
+			return types.Data{}, fmt.Errorf("%w in the contract, height %s", ErrNotRecorded, height)
+		}
+		return data, err
+	}
+
+	entry, ok := MemoIndexMng().Find(util.FromStringToUint64(height))
+	if !ok {
+		return types.Data{}, fmt.Errorf("%w in the memo index, height %s", ErrNotRecorded, height)
+	}
+
+	err := VerifyMemo(xplac, entry)
+	if err != nil {
+		return types.Data{}, err
+	}
+
+	for _, data := range entry.Data {
+		if data.Height == height {
+			return data, nil
+		}
+	}
+
+	return types.Data{}, errors.New("height " + height + " is not found in the memo index")
+}
+
+var memoIndexInstance *MemoIndex
+var memoIndexOnce sync.Once
+
+// The local index of batches which are anchored by memos.
+// Entries are appended to the JSON lines file before anchoring transactions are broadcasted and after they are confirmed,
+// so the batch of the transaction which is included while the gateway is down is still traceable.
+// Only confirmed entries are used to find anchored heights.
+type MemoIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries []types.MemoIndexEntry
+	pending map[string]types.MemoIndexEntry
+}
+
+func MemoIndexMng() *MemoIndex {
+	memoIndexOnce.Do(func() {
+		memoIndexInstance = &MemoIndex{}
+	})
+	return memoIndexInstance
+}
+
+// Load entries of the index file.
+// The file is created when the first entry is appended.
+func (m *MemoIndex) Load(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.path = path
+	m.entries = nil
+	m.pending = make(map[string]types.MemoIndexEntry)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry types.MemoIndexEntry
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return err
+		}
+		m.apply(entry)
+	}
+
+	return scanner.Err()
+}
+
+// Load the index file again.
+// It is used by the process which reads the index appended by the gateway in another process.
+func (m *MemoIndex) Reload() error {
+	m.mu.Lock()
+	path := m.path
+	m.mu.Unlock()
+
+	if path == "" {
+		return errors.New("memo index is not loaded")
+	}
+	return m.Load(path)
+}
+
+func (m *MemoIndex) Append(entry types.MemoIndexEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.path == "" {
+		return errors.New("memo index is not loaded")
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(m.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+
+	m.apply(entry)
+
+	return nil
+}
+
+// The last entry of the transaction decides its status.
+func (m *MemoIndex) apply(entry types.MemoIndexEntry) {
+	switch {
+	case entry.Status == types.MemoIndexPending:
+		m.pending[entry.TxHash] = entry
+	case entry.IsConfirmed():
+		delete(m.pending, entry.TxHash)
+		m.entries = append(m.entries, entry)
+	default:
+		delete(m.pending, entry.TxHash)
+	}
+}
+
+// Get entries of which transactions are not confirmed or failed yet.
+func (m *MemoIndex) Pending() []types.MemoIndexEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []types.MemoIndexEntry
+	for _, entry := range m.pending {
+		pending = append(pending, entry)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return util.FromStringToUint64(pending[i].FromHeight) < util.FromStringToUint64(pending[j].FromHeight)
+	})
+	return pending
+}
+
+// Find the entry which includes the height.
+// If the height is anchored several times, the last entry is used.
+func (m *MemoIndex) Find(height uint64) (types.MemoIndexEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		entry := m.entries[i]
+		if util.FromStringToUint64(entry.FromHeight) <= height && height <= util.FromStringToUint64(entry.ToHeight) {
+			return entry, true
+		}
+	}
+
+	return types.MemoIndexEntry{}, false
+}
+
+// Get the highest anchored height.
+func (m *MemoIndex) Latest() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var latest uint64
+	for _, entry := range m.entries {
+		to := util.FromStringToUint64(entry.ToHeight)
+		if to > latest {
+			latest = to
+		}
+	}
+
+	return latest
+}
+
+// Get heights which are not anchored in the range.
+func (m *MemoIndex) Gaps(fromHeight, toHeight uint64) []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	anchored := make(map[uint64]bool)
+	for _, entry := range m.entries {
+		for _, data := range entry.Data {
+			anchored[util.FromStringToUint64(data.Height)] = true
+		}
+	}
+
+	var gaps []uint64
+	for height := fromHeight; height <= toHeight; height++ {
+		if !anchored[height] {
+			gaps = append(gaps, height)
+		}
+	}
+	return gaps
+}
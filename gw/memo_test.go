@@ -0,0 +1,90 @@
+package gw
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+)
+
+// Load the memo index of the test, and anchor batches by memos until the test ends.
+func useTestMemoSink(t *testing.T, env *testEnv) {
+	err := MemoIndexMng().Load(filepath.Join(t.TempDir(), "memo_index.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.Sink = sinkMemo
+	})
+	t.Cleanup(func() {
+		env.setConfig(t, func(appType *app.AppType) {
+			appType.Config.Anchor.Sink = ""
+		})
+	})
+}
+
+// The pending entry of the memo tx which is refused by the broadcast is failed, so it is not resolved at the next start.
+func TestRefusedMemoTxIsNotPending(t *testing.T) {
+	env := startTestEnv(t)
+	env.newApp(t)
+	useTestMemoSink(t, env)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The test chain only accepts anchoring transactions of the contract, so the memo tx is refused.
+	batch := []types.Data{types.NewData("1", "HASH1", "MERKLE1", "2023-01-01T00:00:00Z")}
+	_, err := broadcastAnchoringTx(ctx, cloneClient(env.senders[0]), types.NewAncoring(batch, "1"))
+	if err == nil {
+		t.Fatal("the memo tx is not refused")
+	}
+
+	if pending := MemoIndexMng().Pending(); len(pending) != 0 {
+		t.Fatalf("%d pending entries are left by the refused memo tx", len(pending))
+	}
+}
+
+// Pending entries are resolved at the same time, so the start is not delayed by the number of them.
+func TestResolvePendingMemoIndexConcurrently(t *testing.T) {
+	env := startTestEnv(t)
+	useTestMemoSink(t, env)
+
+	const timeout = 1000
+	env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.TxConfirmTimeout = timeout
+	})
+	defer env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.TxConfirmTimeout = 10000
+	})
+
+	const count = 4
+	for i := 1; i <= count; i++ {
+		h := strconv.Itoa(i)
+		anchoring := types.NewAncoring([]types.Data{types.NewData(h, "HASH"+h, "MERKLE"+h, "2023-01-01T00:00:00Z")}, h)
+		err := recordMemoIndex(types.MemoIndexPending, anchoring, "UNKNOWNTX"+h)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	err := resolvePendingMemoIndex(context.Background(), env.pubClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Millisecond*timeout*2 {
+		t.Fatalf("pending entries are resolved in %s, want less than twice the confirm timeout", elapsed)
+	}
+	if pending := MemoIndexMng().Pending(); len(pending) != 0 {
+		t.Fatalf("%d pending entries are not resolved", len(pending))
+	}
+	if latest := MemoIndexMng().Latest(); latest != 0 {
+		t.Fatalf("the tx which is not found is confirmed, latest = %d", latest)
+	}
+}
@@ -0,0 +1,228 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const (
+	maintenanceCheckPeriod = 1000
+	dailyTimeLayout        = "15:04"
+)
+
+var pauseInstance *Pause
+var pauseOnce sync.Once
+
+// Pause and resume the gateway at runtime.
+// The gateway is paused manually by the signal or the admin endpoint, or automatically in the maintenance window.
+// While the gateway is paused, the fetcher stops requesting blocks and senders stop taking new batches,
+// but transactions in flight are confirmed and sent again if they fail.
+type Pause struct {
+	mu          sync.Mutex
+	manual      bool
+	maintenance bool
+	since       time.Time
+	// It is closed when the pause state changes, so waiting goroutines check the state again.
+	changed chan struct{}
+}
+
+func PauseMng() *Pause {
+	pauseOnce.Do(func() {
+		pauseInstance = &Pause{
+			changed: make(chan struct{}),
+		}
+	})
+	return pauseInstance
+}
+
+// Pause the gateway manually.
+func (p *Pause) Pause(source string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.manual {
+		return
+	}
+	wasPaused := p.paused()
+	p.manual = true
+	p.notify(wasPaused)
+
+	util.LogInfo(util.BB("gateway is paused by ") + source)
+}
+
+// Resume the gateway which is paused manually.
+// The gateway is still paused in the maintenance window.
+func (p *Pause) Resume(source string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.manual {
+		return
+	}
+	wasPaused := p.paused()
+	p.manual = false
+	p.notify(wasPaused)
+
+	if p.maintenance {
+		util.LogInfo(util.BB("gateway is resumed by ")+source, "but it is still in the maintenance window")
+		return
+	}
+	util.LogInfo(util.BB("gateway is resumed by ") + source)
+}
+
+func (p *Pause) setMaintenance(maintenance bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.maintenance == maintenance {
+		return
+	}
+	wasPaused := p.paused()
+	p.maintenance = maintenance
+	p.notify(wasPaused)
+
+	if maintenance {
+		util.LogInfo(util.BB("maintenance window starts, gateway is paused"))
+	} else {
+		util.LogInfo(util.BB("maintenance window ends"))
+	}
+}
+
+func (p *Pause) IsPaused() bool {
+	paused, _ := p.current()
+	return paused
+}
+
+// Block while the gateway is paused.
+// It returns false if the context is done before the gateway is resumed,
+// so the fetcher does not request the next block after it is canceled.
+func (p *Pause) Wait(ctx context.Context) bool {
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		paused, changed := p.current()
+		if !paused {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Get the status of the pause.
+func (p *Pause) Status() (string, string, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused() {
+		return types.GatewayStatusRunning, "", ""
+	}
+
+	var reasons []string
+	if p.manual {
+		reasons = append(reasons, types.PauseReasonManual)
+	}
+	if p.maintenance {
+		reasons = append(reasons, types.PauseReasonMaintenance)
+	}
+
+	return types.GatewayStatusPaused, strings.Join(reasons, ","), p.since.UTC().Format(time.RFC3339)
+}
+
+func (p *Pause) current() (bool, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.paused(), p.changed
+}
+
+func (p *Pause) paused() bool {
+	return p.manual || p.maintenance
+}
+
+func (p *Pause) notify(wasPaused bool) {
+	if !wasPaused && p.paused() {
+		p.since = time.Now()
+	}
+	state.Mng().SetPaused(p.paused(), p.manual)
+
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// Pause the gateway in maintenance windows of the config.
+// Windows are read in every check, so they can be changed by reloading the config.
+func checkMaintenance(ctx context.Context) {
+	var lastErr string
+	for {
+		windows := app.AppFile().Get().Config.Anchor.MaintenanceWindows
+
+		maintenance, err := inMaintenance(time.Now(), windows)
+		if err != nil {
+			// Warn once until the window is fixed.
+			if err.Error() != lastErr {
+				util.LogWarning("invalid maintenance window:", err)
+				lastErr = err.Error()
+			}
+			maintenance = false
+		} else {
+			lastErr = ""
+		}
+		PauseMng().setMaintenance(maintenance)
+
+		if !sleepContext(ctx, time.Millisecond*time.Duration(maintenanceCheckPeriod)) {
+			return
+		}
+	}
+}
+
+// Check the time is in one of maintenance windows.
+// The window is the range of RFC3339 times, or the daily range of "15:04" times in UTC.
+func inMaintenance(now time.Time, windows []app.MaintenanceWindow) (bool, error) {
+	for _, window := range windows {
+		start, startErr := time.Parse(time.RFC3339, window.Start)
+		end, endErr := time.Parse(time.RFC3339, window.End)
+		if startErr == nil && endErr == nil {
+			if !now.Before(start) && now.Before(end) {
+				return true, nil
+			}
+			continue
+		}
+
+		dailyStart, startErr := time.Parse(dailyTimeLayout, window.Start)
+		dailyEnd, endErr := time.Parse(dailyTimeLayout, window.End)
+		if startErr != nil || endErr != nil {
+			return false, errors.New("start and end must be RFC3339 or 15:04, " + window.Start + "~" + window.End)
+		}
+
+		utc := now.UTC()
+		minutes := utc.Hour()*60 + utc.Minute()
+		startMinutes := dailyStart.Hour()*60 + dailyStart.Minute()
+		endMinutes := dailyEnd.Hour()*60 + dailyEnd.Minute()
+
+		// The daily window can pass midnight such as 23:00~01:00.
+		if startMinutes <= endMinutes {
+			if minutes >= startMinutes && minutes < endMinutes {
+				return true, nil
+			}
+		} else if minutes >= startMinutes || minutes < endMinutes {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
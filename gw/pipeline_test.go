@@ -0,0 +1,700 @@
+package gw
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla.go/client"
+	"github.com/Moonyongjung/xpla.go/key"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	testPrivChainID    = "private-test-1"
+	testPubChainID     = "public-test-1"
+	testBlockApi       = "/blocks"
+	testBlockCount     = 20
+	testCollectCount   = 3
+	testAccountCount   = 3
+	testAccountNumber  = 7
+	testFirstSequence  = 5
+	testTxFailedCode   = 11
+	testSeqMismatchLog = "account sequence mismatch"
+)
+
+// Chains and the config of tests.
+// Managers of endpoints, the rate limiter and the state are singletons of the package,
+// so chains are started once and shared by tests.
+type testEnv struct {
+	priv         *testPrivChain
+	pub          *testPubChain
+	pubClient    *client.XplaClient
+	senders      []*client.XplaClient
+	contractAddr string
+	home         string
+	appType      app.AppType
+	appFilePath  string
+}
+
+var testEnvOnce sync.Once
+var testEnvInstance *testEnv
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	if testEnvInstance != nil {
+		os.RemoveAll(testEnvInstance.home)
+	}
+	os.Exit(code)
+}
+
+func startTestEnv(t *testing.T) *testEnv {
+	testEnvOnce.Do(func() {
+		home, err := os.MkdirTemp("", "anchor-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		priv := newTestPrivChain(t, testPrivChainID, testBlockCount)
+		pub := newTestPubChain()
+
+		contractKey := newTestKey(t)
+		contractAddr, err := key.Bech32AddrString(contractKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var appType app.AppType
+		appType.Home = home
+		appType.Contract.Address = contractAddr
+		appType.Config.PublicChain.ChainID = testPubChainID
+		appType.Config.PublicChain.LCD = httptest.NewServer(pub).URL
+		appType.Config.PublicChain.GasLimit = "300000"
+		appType.Config.PrivateChain.ChainID = testPrivChainID
+		appType.Config.PrivateChain.LCD = httptest.NewServer(priv).URL
+		appType.Config.Anchor.CollectBlockCount = testCollectCount
+		appType.Config.Anchor.BatchQueueSize = 2
+		appType.Config.Anchor.MaxInFlightTx = 2
+		appType.Config.Anchor.TxConfirmTimeout = 10000
+		appType.Config.Anchor.GapCheckPeriod = -1
+		appType.Config.Anchor.AccountReportPeriod = 600000
+
+		bytes, err := yaml.Marshal(appType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		appFilePath := filepath.Join(home, "app.yaml")
+		err = os.WriteFile(appFilePath, bytes, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = app.AppFile().Read(appFilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pubClient := client.NewXplaClient(testPubChainID).WithOptions(client.Options{
+			LcdURL:   appType.Config.PublicChain.LCD,
+			GasLimit: appType.Config.PublicChain.GasLimit,
+		})
+
+		var senders []*client.XplaClient
+		for i := 0; i < testAccountCount; i++ {
+			sender := *pubClient
+			sender.WithPrivateKey(newTestKey(t))
+			senders = append(senders, &sender)
+		}
+
+		testEnvInstance = &testEnv{
+			priv:         priv,
+			pub:          pub,
+			pubClient:    pubClient,
+			senders:      senders,
+			contractAddr: contractAddr,
+			home:         home,
+			appType:      appType,
+			appFilePath:  appFilePath,
+		}
+	})
+
+	if testEnvInstance == nil {
+		t.Fatal("the test environment is not started")
+	}
+	return testEnvInstance
+}
+
+// Change the config of tests.
+func (env *testEnv) setConfig(t *testing.T, set func(appType *app.AppType)) {
+	set(&env.appType)
+
+	bytes, err := yaml.Marshal(env.appType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(env.appFilePath, bytes, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.AppFile().Read(env.appFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Make the app of the gateway which has new channels.
+func (env *testEnv) newApp(t *testing.T) *types.App {
+	anchorConf := app.AppFile().Get().Config.Anchor
+
+	a := &types.App{
+		PubClient: env.pubClient,
+		Senders:   env.senders,
+		Channels:  types.NewChannels(anchorConf.BatchQueueSize, anchorConf.MaxInFlightTx),
+		HomePath:  env.home,
+	}
+
+	for _, sender := range a.Senders {
+		err := syncSequence(sender)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return a
+}
+
+func newTestKey(t *testing.T) key.PrivateKey {
+	mnemonic, err := key.NewMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privKey, err := key.NewPrivKey(mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return privKey
+}
+
+// The private chain which serves blocks by the legacy block API.
+type testPrivChain struct {
+	blocks [][]byte
+	hashes []string
+}
+
+func newTestPrivChain(t *testing.T, chainID string, count int) *testPrivChain {
+	chain := &testPrivChain{}
+
+	var lastBlockID tmtypes.BlockID
+	for height := int64(1); height <= int64(count); height++ {
+		block, blockID := newTestBlock(chainID, height, lastBlockID)
+
+		bytes, err := tmjson.Marshal(coretypes.ResultBlock{BlockID: blockID, Block: block})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chain.blocks = append(chain.blocks, bytes)
+		chain.hashes = append(chain.hashes, block.Hash().String())
+		lastBlockID = blockID
+	}
+
+	return chain
+}
+
+// Make the block which is linked to the last block.
+// The block of height 1 has the empty last block ID.
+func newTestBlock(chainID string, height int64, lastBlockID tmtypes.BlockID) (*tmtypes.Block, tmtypes.BlockID) {
+	testHash := func(name string) []byte {
+		hash := sha256.Sum256([]byte(name + strconv.FormatInt(height, 10)))
+		return hash[:]
+	}
+
+	txs := []tmtypes.Tx{tmtypes.Tx("tx-" + strconv.FormatInt(height, 10))}
+	block := tmtypes.MakeBlock(height, txs, &tmtypes.Commit{}, nil)
+
+	block.Version = tmversion.Consensus{Block: 11}
+	block.ChainID = chainID
+	block.Time = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Second * time.Duration(height))
+	block.LastBlockID = lastBlockID
+	block.ValidatorsHash = testHash("validators")
+	block.NextValidatorsHash = testHash("validators")
+	block.ConsensusHash = testHash("consensus")
+	block.AppHash = testHash("app")
+	block.LastResultsHash = testHash("results")
+	block.ProposerAddress = testHash("proposer")[:20]
+
+	blockID := tmtypes.BlockID{
+		Hash:          block.Hash(),
+		PartSetHeader: tmtypes.PartSetHeader{Total: 1, Hash: testHash("parts")},
+	}
+
+	return block, blockID
+}
+
+func (c *testPrivChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	height := strings.TrimPrefix(r.URL.Path, testBlockApi+"/")
+	if height == latestBlockPath {
+		w.Write(c.blocks[len(c.blocks)-1])
+		return
+	}
+
+	h, err := strconv.Atoi(height)
+	if err != nil || h < 1 {
+		http.Error(w, "invalid height", http.StatusBadRequest)
+		return
+	}
+
+	if h > len(c.blocks) {
+		http.Error(w, requestBiggerHeightErr, http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(c.blocks[h-1])
+}
+
+// The public chain which runs the anchor contract.
+// Sequences of signed transactions are checked like the ante handler, and the first delivered transaction is failed,
+// so the batch of it must be sent again.
+type testPubChain struct {
+	mu         sync.Mutex
+	sequences  map[string]uint64
+	txs        map[string]testPubTx
+	order      []string
+	searches   int
+	anchored   map[string][]string
+	latest     uint64
+	delivered  int
+	failed     int
+	mismatched int
+}
+
+// The delivered transaction, and the height of the block is the order of the delivery.
+type testPubTx struct {
+	code     int
+	sender   string
+	height   int
+	txBytes  []byte
+	contract string
+	msg      []byte
+}
+
+func newTestPubChain() *testPubChain {
+	return &testPubChain{
+		sequences: make(map[string]uint64),
+		txs:       make(map[string]testPubTx),
+		anchored:  make(map[string][]string),
+	}
+}
+
+// Keep the delivered transaction.
+func (c *testPubChain) deliver(hash string, tx testPubTx) {
+	tx.height = len(c.order) + 1
+	c.txs[hash] = tx
+	c.order = append(c.order, hash)
+}
+
+// Forget anchored heights and delivered transactions of the previous test.
+// Sequences of accounts are kept, as the chain does.
+func (c *testPubChain) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.txs = make(map[string]testPubTx)
+	c.order = nil
+	c.searches = 0
+	c.anchored = make(map[string][]string)
+	c.delivered = 0
+	c.failed = 0
+	c.mismatched = 0
+}
+
+func (c *testPubChain) sequence(addr string) uint64 {
+	seq, ok := c.sequences[addr]
+	if !ok {
+		return testFirstSequence
+	}
+	return seq
+}
+
+func (c *testPubChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/cosmos/auth/v1beta1/accounts/"):
+		addr := strings.TrimPrefix(r.URL.Path, "/cosmos/auth/v1beta1/accounts/")
+		fmt.Fprintf(w, `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"%s","pub_key":null,"account_number":"%d","sequence":"%d"}}`,
+			addr, testAccountNumber, c.sequence(addr))
+
+	case r.URL.Path == "/cosmos/tx/v1beta1/txs" && r.Method == http.MethodPost:
+		c.broadcast(w, r)
+
+	case strings.HasPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/"):
+		hash := strings.TrimPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/")
+		tx, ok := c.txs[hash]
+		if !ok {
+			http.Error(w, `{"code":5,"message":"tx not found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"tx":{"body":{"memo":""},"auth_info":{"fee":{"amount":[{"denom":"axpla","amount":"1"}]}}},"tx_response":{"height":"%d","txhash":"%s","code":%d,"raw_log":"","gas_wanted":"300000","gas_used":"100000"}}`,
+			tx.height, hash, tx.code)
+
+	case r.URL.Path == "/cosmos/tx/v1beta1/txs":
+		c.searchTxs(w, r)
+
+	case strings.HasPrefix(r.URL.Path, pubBlocksPath):
+		height, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, pubBlocksPath))
+		var block proofBlockResponse
+		for _, hash := range c.order {
+			if tx := c.txs[hash]; tx.height == height {
+				block.Block.Data.Txs = append(block.Block.Data.Txs, base64.StdEncoding.EncodeToString(tx.txBytes))
+			}
+		}
+		json.NewEncoder(w).Encode(block)
+
+	case strings.Contains(r.URL.Path, "/smart/"):
+		query, _ := base64.StdEncoding.DecodeString(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		if string(query) != types.QueryLatestBlockMsg {
+			http.Error(w, `{"code":2,"message":"unknown query"}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"latest_height":"%d"}}`, c.latest)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Check the sequence of the transaction and execute the anchoring message.
+func (c *testPubChain) broadcast(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TxBytes []byte `json:"tx_bytes"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Messages of the wasm module are not registered in the codec of the XPLA client, so the tx is decoded by parts.
+	var txRaw txtypes.TxRaw
+	var body txtypes.TxBody
+	var authInfo txtypes.AuthInfo
+	var msg wasmtypes.MsgExecuteContract
+	for _, decode := range []func() error{
+		func() error { return txRaw.Unmarshal(req.TxBytes) },
+		func() error { return body.Unmarshal(txRaw.BodyBytes) },
+		func() error { return authInfo.Unmarshal(txRaw.AuthInfoBytes) },
+		func() error {
+			if len(body.Messages) != 1 || len(authInfo.SignerInfos) != 1 || body.Messages[0].TypeUrl != "/cosmwasm.wasm.v1.MsgExecuteContract" {
+				return fmt.Errorf("not the anchoring tx")
+			}
+			return msg.Unmarshal(body.Messages[0].Value)
+		},
+	} {
+		err = decode()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	hash := txHash(req.TxBytes)
+	sequence := authInfo.SignerInfos[0].Sequence
+	if sequence != c.sequence(msg.Sender) {
+		c.mismatched++
+		fmt.Fprintf(w, `{"tx_response":{"txhash":"%s","code":32,"raw_log":"%s"}}`, hash, testSeqMismatchLog)
+		return
+	}
+	c.sequences[msg.Sender] = sequence + 1
+
+	code := 0
+	c.delivered++
+	if c.delivered == 1 {
+		code = testTxFailedCode
+		c.failed++
+	} else {
+		var execMsg struct {
+			Anchoring types.Anchoring `json:"anchoring"`
+		}
+		err = json.Unmarshal(msg.Msg, &execMsg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, data := range execMsg.Anchoring.Data {
+			c.anchored[data.Height] = append(c.anchored[data.Height], data.BlockHash)
+		}
+		if latest, _ := strconv.ParseUint(execMsg.Anchoring.Latest, 10, 64); latest > c.latest {
+			c.latest = latest
+		}
+	}
+
+	c.deliver(hash, testPubTx{code: code, sender: msg.Sender, txBytes: req.TxBytes, contract: msg.Contract, msg: msg.Msg})
+	fmt.Fprintf(w, `{"tx_response":{"txhash":"%s","code":0}}`, hash)
+}
+
+// Search anchoring transactions of the contract by events, the order and the offset like the tx service of the cosmos SDK.
+func (c *testPubChain) searchTxs(w http.ResponseWriter, r *http.Request) {
+	c.searches++
+
+	query := r.URL.Query()
+	events := query["events"]
+	if len(events) != 2 || events[1] != wasmMethodEvent+"='"+anchoringMethod+"'" || !strings.HasPrefix(events[0], contractAddressEvent+"='") {
+		http.Error(w, `{"code":3,"message":"unexpected events"}`, http.StatusBadRequest)
+		return
+	}
+	contractAddr := strings.TrimSuffix(strings.TrimPrefix(events[0], contractAddressEvent+"='"), "'")
+
+	offset, _ := strconv.Atoi(query.Get("pagination.offset"))
+	limit, _ := strconv.Atoi(query.Get("pagination.limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var hashes []string
+	for _, hash := range c.order {
+		if c.txs[hash].contract == contractAddr {
+			hashes = append(hashes, hash)
+		}
+	}
+	if query.Get("order_by") == txtypes.OrderBy_ORDER_BY_DESC.String() {
+		for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+			hashes[i], hashes[j] = hashes[j], hashes[i]
+		}
+	}
+
+	res := struct {
+		Txs         []interface{}   `json:"txs"`
+		TxResponses []proofTxResult `json:"tx_responses"`
+	}{Txs: []interface{}{}, TxResponses: []proofTxResult{}}
+	for i := offset; i < len(hashes) && i < offset+limit; i++ {
+		tx := c.txs[hashes[i]]
+		res.Txs = append(res.Txs, map[string]interface{}{
+			"body": map[string]interface{}{
+				"messages": []interface{}{map[string]interface{}{
+					"@type":    "/cosmwasm.wasm.v1.MsgExecuteContract",
+					"sender":   tx.sender,
+					"contract": tx.contract,
+					"msg":      json.RawMessage(tx.msg),
+				}},
+				"memo": "",
+			},
+		})
+		res.TxResponses = append(res.TxResponses, proofTxResult{Height: strconv.Itoa(tx.height), TxHash: hashes[i], Code: tx.code})
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+// The fetcher, senders and the confirmer anchor every block once by the sequence of each account,
+// and the batch of the failed transaction is anchored again.
+// Run with -race.
+func TestPipeline(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+	a.Channels.ConfirmedTx = make(chan types.Anchoring, cap(a.Channels.PendingTx))
+
+	err := InitBlockAdapter(testBlockApi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Mng().SetNextHeight(1)
+	env.pub.reset()
+
+	supervisor := NewSupervisor(context.Background())
+	defer supervisor.Stop(nil)
+
+	for i, sender := range a.Senders {
+		supervisor.Go("sender-"+strconv.Itoa(i), SendAnchoringTx(a, sender, ""))
+	}
+	supervisor.Go("confirmer", confirmAnchoringTx(a, nil))
+	supervisor.Go("fetcher", func(ctx context.Context) error {
+		return request(ctx, a, testBlockApi, testBlockCount)
+	})
+
+	confirmed := make(map[string]int)
+	timeout := time.After(time.Second * 30)
+	for len(confirmed) < testBlockCount {
+		select {
+		case anchoring := <-a.Channels.ConfirmedTx:
+			for _, data := range anchoring.Data {
+				confirmed[data.Height]++
+			}
+		case <-supervisor.Done():
+			t.Fatalf("supervisor is stopped: %v", supervisor.Err())
+		case <-timeout:
+			t.Fatalf("confirmed heights = %d, want %d", len(confirmed), testBlockCount)
+		}
+	}
+
+	supervisor.Stop(nil)
+
+	env.pub.mu.Lock()
+	defer env.pub.mu.Unlock()
+
+	for height := 1; height <= testBlockCount; height++ {
+		h := strconv.Itoa(height)
+		if confirmed[h] != 1 {
+			t.Errorf("height %s is confirmed %d times", h, confirmed[h])
+		}
+
+		hashes := env.pub.anchored[h]
+		if len(hashes) != 1 || hashes[0] != env.priv.hashes[height-1] {
+			t.Errorf("anchored hashes of height %s = %v, want [%s]", h, hashes, env.priv.hashes[height-1])
+		}
+	}
+
+	if env.pub.failed != 1 {
+		t.Errorf("failed txs = %d, want 1", env.pub.failed)
+	}
+	if env.pub.mismatched != 0 {
+		t.Errorf("sequence mismatches = %d, want 0", env.pub.mismatched)
+	}
+
+	for _, sender := range a.Senders {
+		addr, err := senderAddress(sender)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := state.Mng().Sequence(addr), env.pub.sequence(addr); got != want {
+			t.Errorf("sequence of %s = %d, want %d", addr, got, want)
+		}
+	}
+}
+
+func batchHeights(anchoring types.Anchoring) []string {
+	var heights []string
+	for _, data := range anchoring.Data {
+		heights = append(heights, data.Height)
+	}
+	return heights
+}
+
+// The fetcher which stops while the queue of batches is full requests blocks of the unsent batch again,
+// and the next fetcher does not mix them with blocks of the stopped one.
+func TestFetcherRewind(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	err := InitBlockAdapter(testBlockApi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Mng().SetNextHeight(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fetched := make(chan error, 1)
+	go func() {
+		fetched <- request(ctx, a, testBlockApi, 0)
+	}()
+
+	// The fetcher waits for the queue with the third batch.
+	deadline := time.Now().Add(time.Second * 10)
+	for len(a.Channels.AnchringTx) < cap(a.Channels.AnchringTx) || state.Mng().NextHeight() < 3*testCollectCount {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued batches = %d, next height = %d", len(a.Channels.AnchringTx), state.Mng().NextHeight())
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	time.Sleep(time.Millisecond * 200)
+
+	cancel()
+	if err := <-fetched; err != nil {
+		t.Fatal(err)
+	}
+
+	unsent := uint64(cap(a.Channels.AnchringTx)*testCollectCount + 1)
+	if got := state.Mng().NextHeight(); got != unsent {
+		t.Fatalf("next height = %d, want %d", got, unsent)
+	}
+
+	for i := 0; i < cap(a.Channels.AnchringTx); i++ {
+		<-a.Channels.AnchringTx
+	}
+
+	endHeight := unsent + testCollectCount - 1
+	err = request(context.Background(), a, testBlockApi, endHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for height := unsent; height <= endHeight; height++ {
+		want = append(want, strconv.FormatUint(height, 10))
+	}
+
+	select {
+	case anchoring := <-a.Channels.AnchringTx:
+		if got := batchHeights(anchoring); !reflect.DeepEqual(got, want) {
+			t.Fatalf("heights of the batch = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("the batch of the next fetcher is not sent")
+	}
+}
+
+// The batch which is sent again after the newer batch keeps the latest height of the newer one.
+func TestRetryKeepsSubmittedLatest(t *testing.T) {
+	env := startTestEnv(t)
+	env.newApp(t)
+
+	base := state.Mng().SubmittedLatest()
+	testBatch := func(first uint64) types.Anchoring {
+		var batch []types.Data
+		for height := first; height < first+testCollectCount; height++ {
+			h := strconv.FormatUint(height, 10)
+			batch = append(batch, types.NewData(h, "HASH"+h, "MERKLE"+h, "2023-01-01T00:00:00Z"))
+		}
+		return types.NewAncoring(batch, batch[len(batch)-1].Height)
+	}
+
+	older := testBatch(base + 1)
+	newer := testBatch(base + 1 + testCollectCount)
+
+	xplac := cloneClient(env.senders[0]).WithBroadcastMode("sync")
+	for _, tc := range []struct {
+		batch  types.Anchoring
+		latest string
+	}{
+		{newer, newer.Latest},
+		{older, newer.Latest},
+	} {
+		pendingTxs, err := broadcastAnchoringTx(context.Background(), xplac, tc.batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pendingTxs) != 1 || pendingTxs[0].Anchoring.Latest != tc.latest {
+			t.Fatalf("latest height of the batch %s~ = %s, want %s", tc.batch.Data[0].Height, pendingTxs[0].Anchoring.Latest, tc.latest)
+		}
+		state.Mng().RemovePendingTx(pendingTxs[0].TxHash)
+	}
+}
@@ -0,0 +1,322 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const (
+	defaultHealthCheckPeriod = 10000
+	defaultMaxLagBlocks      = 10
+	latestBlockPath          = "latest"
+)
+
+var privEndpointInstance *PrivEndpoint
+var privEndpointOnce sync.Once
+
+// Manage LCD endpoints of the private chain.
+// Endpoints are requested in the configured order, and unhealthy endpoints are ejected.
+// The endpoint is unhealthy if it is not reachable or its height is far behind the highest known height.
+type PrivEndpoint struct {
+	mu               sync.Mutex
+	endpoints        []string
+	healthy          map[string]bool
+	heights          map[string]uint64
+	requireAgreement bool
+}
+
+func PrivEndpointMng() *PrivEndpoint {
+	privEndpointOnce.Do(func() {
+		privConf := app.AppFile().Get().Config.PrivateChain
+
+		privEndpointInstance = &PrivEndpoint{
+			endpoints:        uniqueLCDs(privConf.LCD, privConf.LCDs),
+			healthy:          make(map[string]bool),
+			heights:          make(map[string]uint64),
+			requireAgreement: privConf.RequireAgreement,
+		}
+
+		for _, endpoint := range privEndpointInstance.endpoints {
+			privEndpointInstance.healthy[endpoint] = true
+		}
+
+		if privEndpointInstance.requireAgreement && len(privEndpointInstance.endpoints) < 2 {
+			util.LogWarning("the agreement of block hashes needs two endpoints of the private chain at least, it is disabled")
+			privEndpointInstance.requireAgreement = false
+		}
+	})
+	return privEndpointInstance
+}
+
+// Change endpoints by the reloaded config.
+// New endpoints are healthy until they fail.
+func (p *PrivEndpoint) setEndpoints(endpoints []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.endpoints = endpoints
+	for _, endpoint := range endpoints {
+		if _, ok := p.healthy[endpoint]; !ok {
+			p.healthy[endpoint] = true
+		}
+	}
+}
+
+func (p *PrivEndpoint) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string{}, p.endpoints...)
+}
+
+// Get the health and the latest height of each endpoint.
+func (p *PrivEndpoint) Status() []types.EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var status []types.EndpointStatus
+	for _, endpoint := range p.endpoints {
+		status = append(status, types.NewEndpointStatus(endpoint, p.healthy[endpoint], p.heights[endpoint]))
+	}
+
+	return status
+}
+
+func (p *PrivEndpoint) RequireAgreement() bool {
+	return p.requireAgreement
+}
+
+// Get endpoints in order which healthy endpoints are first.
+// Ejected endpoints are still requested if all healthy endpoints fail, so the gateway does not stop by the stale health.
+func (p *PrivEndpoint) ordered(exclude string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy, ejected []string
+	for _, endpoint := range p.endpoints {
+		if endpoint == exclude {
+			continue
+		}
+
+		if p.healthy[endpoint] {
+			healthy = append(healthy, endpoint)
+		} else {
+			ejected = append(ejected, endpoint)
+		}
+	}
+
+	return append(healthy, ejected...)
+}
+
+// Request block info to endpoints except the excluded endpoint.
+// Return the response and the endpoint which responds.
+// The endpoint which is behind the best endpoint is skipped, and the next endpoint is requested.
+// If every endpoint is behind, the response of the first one is used, so the block is regarded as not created yet.
+func (p *PrivEndpoint) Request(blockApi, blockHeight, exclude string) ([]byte, string, error) {
+	var lastErr error = errors.New("no endpoint of the private chain")
+	var behindBody []byte
+	var behindEndpoint string
+
+	for _, endpoint := range p.ordered(exclude) {
+		responseBody, err := doRequestTo(endpoint, blockApi, blockHeight)
+		if err != nil {
+			p.setHealth(endpoint, false, err)
+			lastErr = err
+			continue
+		}
+
+		if reason, eject := p.behind(endpoint, blockHeight, responseBody); reason != "" {
+			if eject {
+				p.setHealth(endpoint, false, reason)
+			}
+			if behindBody == nil {
+				behindBody, behindEndpoint = responseBody, endpoint
+			}
+			continue
+		}
+
+		return responseBody, endpoint, nil
+	}
+
+	if behindBody != nil {
+		return behindBody, behindEndpoint, nil
+	}
+
+	return nil, "", lastErr
+}
+
+// Get the reason why the endpoint is behind the highest height of other endpoints, and record the height of the endpoint.
+// The endpoint is behind if it does not have the block which another endpoint has, or its latest height is lower than the highest height.
+// It is ejected like the failed endpoint if it is behind more than the max lag.
+func (p *PrivEndpoint) behind(endpoint, blockHeight string, responseBody []byte) (string, bool) {
+	maxLag := app.AppFile().Get().Config.PrivateChain.MaxLagBlocks
+	if maxLag <= 0 {
+		maxLag = defaultMaxLagBlocks
+	}
+
+	notCreated := strings.Contains(string(responseBody), requestBiggerHeightErr)
+
+	var height uint64
+	if !notCreated {
+		header, err := ParseBlock(responseBody)
+		if err != nil {
+			return "", false
+		}
+		height = util.FromStringToUint64(header.Height)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if height > p.heights[endpoint] {
+		p.heights[endpoint] = height
+	}
+
+	var highest uint64
+	for _, other := range p.endpoints {
+		if other != endpoint && p.heights[other] > highest {
+			highest = p.heights[other]
+		}
+	}
+
+	isBehind := (notCreated && blockHeight != latestBlockPath && util.FromStringToUint64(blockHeight) <= highest) ||
+		(blockHeight == latestBlockPath && height < highest)
+	if !isBehind {
+		return "", false
+	}
+
+	known := p.heights[endpoint]
+	return "height=" + util.FromUint64ToString(known) + ", highest=" + util.FromUint64ToString(highest), known+uint64(maxLag) < highest
+}
+
+// Check another endpoint has the same block hash of the height.
+func (p *PrivEndpoint) CheckAgreement(blockApi, blockHeight, privLcd string, responseBody []byte) error {
+	otherBody, other, err := p.Request(blockApi, blockHeight, privLcd)
+	if err != nil {
+		return err
+	}
+
+	hash := parseBlockHash(responseBody)
+	otherHash := parseBlockHash(otherBody)
+	if hash == "" || hash != otherHash {
+		return errors.New("height " + blockHeight + ", " + privLcd + "=" + hash + ", " + other + "=" + otherHash)
+	}
+
+	return nil
+}
+
+func (p *PrivEndpoint) setHealth(endpoint string, healthy bool, reason interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.healthy[endpoint] == healthy {
+		return
+	}
+	p.healthy[endpoint] = healthy
+
+	if healthy {
+		util.LogInfo(util.BB("private chain endpoint is recovered="), endpoint)
+	} else {
+		util.LogWarning("private chain endpoint is ejected,", endpoint, reason)
+	}
+}
+
+// Check reachability and the latest height of each endpoint periodically.
+// Settings are read in every check, so they can be changed by reloading the config.
+func checkPrivEndpoints(ctx context.Context, blockApi string) {
+	p := PrivEndpointMng()
+
+	for {
+		privConf := app.AppFile().Get().Config.PrivateChain
+
+		period := privConf.HealthCheckPeriod
+		if period <= 0 {
+			period = defaultHealthCheckPeriod
+		}
+
+		maxLag := privConf.MaxLagBlocks
+		if maxLag <= 0 {
+			maxLag = defaultMaxLagBlocks
+		}
+
+		// A single endpoint has nothing to fail over.
+		endpoints := p.list()
+		if len(endpoints) < 2 {
+			if !sleepContext(ctx, time.Millisecond*time.Duration(period)) {
+				return
+			}
+			continue
+		}
+
+		var highest uint64
+		errs := make(map[string]error)
+
+		for _, endpoint := range endpoints {
+			height, err := queryPrivLatestHeight(endpoint, blockApi)
+			if err != nil {
+				errs[endpoint] = err
+				continue
+			}
+
+			p.mu.Lock()
+			p.heights[endpoint] = height
+			p.mu.Unlock()
+
+			if height > highest {
+				highest = height
+			}
+		}
+
+		for _, endpoint := range endpoints {
+			if err, ok := errs[endpoint]; ok {
+				p.setHealth(endpoint, false, err)
+				continue
+			}
+
+			p.mu.Lock()
+			height := p.heights[endpoint]
+			p.mu.Unlock()
+
+			if height+uint64(maxLag) < highest {
+				p.setHealth(endpoint, false, "height="+util.FromUint64ToString(height)+", highest="+util.FromUint64ToString(highest))
+				continue
+			}
+			p.setHealth(endpoint, true, nil)
+		}
+
+		if !sleepContext(ctx, time.Millisecond*time.Duration(period)) {
+			return
+		}
+	}
+}
+
+// Query the latest block height of the endpoint.
+func queryPrivLatestHeight(endpoint, blockApi string) (uint64, error) {
+	responseBody, err := doRequestTo(endpoint, blockApi, latestBlockPath)
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := ParseBlock(responseBody)
+	if err != nil {
+		return 0, err
+	}
+
+	return util.FromStringToUint64(header.Height), nil
+}
+
+func parseBlockHash(responseBody []byte) string {
+	header, err := ParseBlock(responseBody)
+	if err != nil {
+		return ""
+	}
+
+	return header.Hash
+}
@@ -0,0 +1,84 @@
+package gw
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+// The endpoint which does not have the block of another endpoint is skipped,
+// and it is ejected if it is behind more than the max lag.
+func TestPrivEndpointBehind(t *testing.T) {
+	env := startTestEnv(t)
+
+	err := InitBlockAdapter(testBlockApi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := env.appType.Config.PrivateChain.LCD
+	staleServer := httptest.NewServer(newTestPrivChain(t, testPrivChainID, 5))
+	defer staleServer.Close()
+	nearServer := httptest.NewServer(newTestPrivChain(t, testPrivChainID, testBlockCount-2))
+	defer nearServer.Close()
+	stale, near := staleServer.URL, nearServer.URL
+
+	p := PrivEndpointMng()
+	defer p.setEndpoints([]string{fresh})
+
+	healthy := func(endpoint string) bool {
+		for _, status := range p.Status() {
+			if status.URL == endpoint {
+				return status.Healthy
+			}
+		}
+		t.Fatalf("%s is not found", endpoint)
+		return false
+	}
+
+	// The stale endpoint is behind the latest height of the fresh endpoint more than the max lag.
+	p.setEndpoints([]string{stale, fresh})
+	_, endpoint, err := p.Request(testBlockApi, latestBlockPath, stale)
+	if err != nil || endpoint != fresh {
+		t.Fatalf("endpoint = %s, err = %v", endpoint, err)
+	}
+
+	responseBody, endpoint, err := p.Request(testBlockApi, "8", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header, err := ParseBlock(responseBody); err != nil || header.Height != "8" || endpoint != fresh {
+		t.Fatalf("the block of height 8 is not responded by the fresh endpoint, endpoint = %s, err = %v", endpoint, err)
+	}
+	if healthy(stale) {
+		t.Fatal("the stale endpoint is not ejected")
+	}
+
+	// The near endpoint is behind less than the max lag, so it is skipped but not ejected.
+	p.setEndpoints([]string{near, fresh})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checkPrivEndpoints(ctx, testBlockApi)
+
+	for _, height := range []string{"19", latestBlockPath} {
+		responseBody, endpoint, err := p.Request(testBlockApi, height, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header, err := ParseBlock(responseBody); err != nil || endpoint != fresh {
+			t.Fatalf("the block of %s is responded by %s, height = %s, err = %v", height, endpoint, header.Height, err)
+		}
+	}
+	if !healthy(near) {
+		t.Fatal("the endpoint which is behind less than the max lag is ejected")
+	}
+
+	// If every endpoint is behind, the block is not created yet.
+	responseBody, _, err = p.Request(testBlockApi, "21", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseBlock(responseBody); err == nil {
+		t.Fatal("the block which is not created is responded")
+	}
+}
@@ -0,0 +1,560 @@
+package gw
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+)
+
+const (
+	ProofBundleVersion   = 2
+	proofSearchLimit     = 100
+	contractAddressEvent = "wasm._contract_address"
+	wasmMethodEvent      = "wasm.method"
+	anchoringMethod      = "anchoring"
+	pubTxsPath           = "/cosmos/tx/v1beta1/txs"
+	pubBlocksPath        = "/cosmos/base/tendermint/v1beta1/blocks/"
+)
+
+// Names of checks of the proof bundle.
+const (
+	ProofCheckVersion      = "version"
+	ProofCheckHeader       = "header"
+	ProofCheckTxInclusion  = "tx inclusion"
+	ProofCheckBatch        = "batch"
+	ProofCheckTx           = "tx"
+	ProofCheckTxBytes      = "tx bytes"
+	ProofCheckAnchoring    = "anchoring"
+	ProofCheckPublicTx     = "public tx"
+	ProofCheckPublicRecord = "public record"
+)
+
+// The transaction of the response of the public chain.
+// Only fields which prove the anchoring are decoded.
+type proofTx struct {
+	Body struct {
+		Messages []proofTxMsg `json:"messages"`
+		Memo     string       `json:"memo"`
+	} `json:"body"`
+}
+
+// The message of the transaction.
+// MsgExecuteContract has the contract and the message, and MsgExec of the authz mode wraps it.
+type proofTxMsg struct {
+	Contract string          `json:"contract"`
+	Msg      json.RawMessage `json:"msg"`
+	Msgs     []proofTxMsg    `json:"msgs"`
+}
+
+type proofTxResult struct {
+	Height string `json:"height"`
+	TxHash string `json:"txhash"`
+	Code   int    `json:"code"`
+}
+
+type proofTxResponse struct {
+	Tx         proofTx       `json:"tx"`
+	TxResponse proofTxResult `json:"tx_response"`
+}
+
+type proofTxsResponse struct {
+	Txs         []proofTx       `json:"txs"`
+	TxResponses []proofTxResult `json:"tx_responses"`
+}
+
+// The block of the public chain which has raw transactions.
+type proofBlockResponse struct {
+	Block struct {
+		Data struct {
+			Txs []string `json:"txs"`
+		} `json:"data"`
+	} `json:"block"`
+}
+
+// Export the proof bundle of the height.
+// The anchoring transaction is found in the memo index in the memo sink,
+// otherwise it is searched in transactions of the contract.
+func ExportProof(a *types.App, contractAddr, blockApi, height string) (types.ProofBundle, error) {
+	var bundle types.ProofBundle
+
+	res, err := DoRequest(a, blockApi, height)
+	if err != nil {
+		return bundle, err
+	}
+
+	header, err := ParseBlock(res)
+	if err != nil {
+		return bundle, err
+	}
+
+	recorded, err := QueryRecordedBlockData(a.PubClient, contractAddr, height)
+	if err != nil {
+		return bundle, err
+	}
+
+	conf := app.AppFile().Get().Config
+
+	var txHash string
+	var batch []types.Data
+	if IsMemoSink() {
+		entry, ok := MemoIndexMng().Find(util.FromStringToUint64(height))
+		if !ok {
+			return bundle, errors.New("height " + height + " is not found in the memo index")
+		}
+		txHash = entry.TxHash
+		batch = entry.Data
+	} else {
+		txHash, err = FindAnchoringTx(a.PubClient, contractAddr, recorded)
+		if err != nil {
+			return bundle, err
+		}
+	}
+
+	var txRes string
+	err = withPubFailover(a.PubClient, func(xplac *client.XplaClient) error {
+		var err error
+		txRes, err = queryProofTx(xplac, txHash)
+		return err
+	})
+	if err != nil {
+		return bundle, err
+	}
+
+	var tx proofTxResponse
+	err = json.Unmarshal([]byte(txRes), &tx)
+	if err != nil {
+		return bundle, err
+	}
+
+	if !IsMemoSink() {
+		batch = anchoredData(tx.Tx.Body.Messages, contractAddr)
+	}
+
+	txBytes, err := queryPubTxBytes(a.PubClient, tx.TxResponse.Height, tx.TxResponse.TxHash)
+	if err != nil {
+		return bundle, err
+	}
+
+	bundle.Version = ProofBundleVersion
+	bundle.Sink = sinkContract
+	if IsMemoSink() {
+		bundle.Sink = sinkMemo
+	} else {
+		bundle.ContractAddress = contractAddr
+	}
+	bundle.PrivateChainID = conf.PrivateChain.ChainID
+	bundle.PublicChainID = conf.PublicChain.ChainID
+	bundle.Header = header
+	bundle.Record = recorded
+	bundle.Batch = batch
+	bundle.TxHash = tx.TxResponse.TxHash
+	bundle.PublicHeight = tx.TxResponse.Height
+	bundle.Tx = json.RawMessage(txRes)
+	bundle.TxBytes = txBytes
+	bundle.ExportedAt = time.Now().UTC().Format(time.RFC3339)
+
+	return bundle, nil
+}
+
+// Find the hash of the successful transaction which anchors the recorded block info.
+// Anchoring transactions of the contract are searched from the latest one, and the search stops at the first match.
+// The XPLA client queries only one event without the order, so the LCD is requested directly.
+func FindAnchoringTx(xplac *client.XplaClient, contractAddr string, recorded types.Data) (string, error) {
+	seen := make(map[string]bool)
+
+	for offset := 0; ; offset += proofSearchLimit {
+		query := url.Values{}
+		query.Add("events", contractAddressEvent+"='"+contractAddr+"'")
+		query.Add("events", wasmMethodEvent+"='"+anchoringMethod+"'")
+		query.Set("order_by", txtypes.OrderBy_ORDER_BY_DESC.String())
+		query.Set("pagination.offset", strconv.Itoa(offset))
+		query.Set("pagination.limit", strconv.Itoa(proofSearchLimit))
+
+		var res []byte
+		err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+			var err error
+			res, err = queryPubLcd(xplac.GetLcdURL(), pubTxsPath, query)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+
+		var txs proofTxsResponse
+		err = json.Unmarshal(res, &txs)
+		if err != nil {
+			return "", err
+		}
+
+		for i, txRes := range txs.TxResponses {
+			// The LCD which does not support pages returns the same transactions.
+			if seen[txRes.TxHash] {
+				return "", errors.New("the anchoring tx of height " + recorded.Height + " is not found, the LCD returns the same page of txs")
+			}
+			seen[txRes.TxHash] = true
+
+			if txRes.Code != 0 || i >= len(txs.Txs) {
+				continue
+			}
+
+			for _, data := range anchoredData(txs.Txs[i].Body.Messages, contractAddr) {
+				if sameData(data, recorded) {
+					return txRes.TxHash, nil
+				}
+			}
+		}
+
+		if len(txs.TxResponses) < proofSearchLimit {
+			return "", errors.New("the anchoring tx of height " + recorded.Height + " is not found in txs of the contract")
+		}
+	}
+}
+
+// Get the raw transaction (base64) of the hash in the public block of the height.
+func queryPubTxBytes(xplac *client.XplaClient, height, hash string) (string, error) {
+	var res []byte
+	err := withPubFailover(xplac, func(xplac *client.XplaClient) error {
+		var err error
+		res, err = queryPubLcd(xplac.GetLcdURL(), pubBlocksPath+height, nil)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var block proofBlockResponse
+	err = json.Unmarshal(res, &block)
+	if err != nil {
+		return "", err
+	}
+
+	for _, tx := range block.Block.Data.Txs {
+		txbytes, err := base64.StdEncoding.DecodeString(tx)
+		if err != nil {
+			continue
+		}
+		if txHash(txbytes) == strings.ToUpper(hash) {
+			return tx, nil
+		}
+	}
+
+	return "", errors.New("the tx " + hash + " is not found in the public block of height " + height)
+}
+
+// Verify the proof bundle without requests.
+func VerifyProofBundle(bundle types.ProofBundle) types.ProofVerifyResult {
+	result := types.NewProofVerifyResult(bundle, "")
+
+	var versionErr error
+	if bundle.Version != ProofBundleVersion {
+		versionErr = errors.New("unsupported version " + strconv.Itoa(bundle.Version))
+	}
+	result.Add(types.NewProofCheck(ProofCheckVersion, versionErr))
+	if versionErr != nil {
+		return result
+	}
+
+	result.Add(types.NewProofCheck(ProofCheckHeader, checkProofHeader(bundle)))
+
+	// The transaction of the private chain is linked to the data hash of the header.
+	if bundle.TxProof != nil {
+		result.Add(types.NewProofCheck(ProofCheckTxInclusion, CheckTxProof(bundle.Header, *bundle.TxProof)))
+	}
+	result.Add(types.NewProofCheck(ProofCheckBatch, checkProofBatch(bundle)))
+
+	var tx proofTxResponse
+	txErr := json.Unmarshal(bundle.Tx, &tx)
+	if txErr == nil {
+		txErr = checkProofTxResult(bundle, tx.TxResponse)
+	}
+	result.Add(types.NewProofCheck(ProofCheckTx, txErr))
+	if txErr != nil {
+		return result
+	}
+
+	// The anchoring is checked by the raw transaction, because it is bound to the tx hash.
+	signedTx, txBytesErr := checkProofTxBytes(bundle)
+	result.Add(types.NewProofCheck(ProofCheckTxBytes, txBytesErr))
+	if txBytesErr != nil {
+		return result
+	}
+
+	result.Add(types.NewProofCheck(ProofCheckAnchoring, checkProofAnchoring(bundle, signedTx)))
+
+	return result
+}
+
+// Verify the proof bundle, and check it again against the public chain by the LCD.
+// The transaction and the record of the contract are queried, so the bundle is not trusted.
+func VerifyProofBundleOnline(bundle types.ProofBundle, xplac *client.XplaClient, publicLcd string) types.ProofVerifyResult {
+	result := VerifyProofBundle(bundle)
+	result.PublicLCD = publicLcd
+	if !result.Valid {
+		return result
+	}
+
+	xplac.WithURL(publicLcd)
+
+	var tx proofTxResponse
+	res, txErr := queryProofTx(xplac, bundle.TxHash)
+	if txErr == nil {
+		txErr = json.Unmarshal([]byte(res), &tx)
+	}
+	if txErr == nil {
+		txErr = checkProofTxResult(bundle, tx.TxResponse)
+	}
+	if txErr == nil {
+		txErr = checkProofAnchoring(bundle, tx.Tx)
+	}
+	result.Add(types.NewProofCheck(ProofCheckPublicTx, txErr))
+
+	// The record of the contract can be overwritten by the owner after anchoring, so it is checked again.
+	if bundle.Sink == sinkContract {
+		recorded, recordErr := queryBlockDataFrom(xplac, bundle.ContractAddress, bundle.Record.Height)
+		if recordErr == nil && !sameData(recorded, bundle.Record) {
+			recordErr = errors.New("the record of the contract is not matched, block hash=" + recorded.BlockHash)
+		}
+		result.Add(types.NewProofCheck(ProofCheckPublicRecord, recordErr))
+	}
+
+	// The transaction of the hash is found in the public chain which the auditor chooses.
+	result.Authenticated = result.Valid
+
+	return result
+}
+
+// The header of the private chain is matched with the record.
+func checkProofHeader(bundle types.ProofBundle) error {
+	if bundle.Header.ChainID != "" && bundle.Header.ChainID != bundle.PrivateChainID {
+		return errors.New("the chain ID of the header is " + bundle.Header.ChainID)
+	}
+
+	// The bundle does not have the previous block, so the last block ID is not checked.
+	mismatches := CompareBlock(bundle.Header, bundle.PrivateChainID, "", NormalizeData(bundle.Record))
+	if len(mismatches) != 0 {
+		return errors.New("mismatched fields=" + strings.Join(mismatches, ","))
+	}
+
+	return nil
+}
+
+// The record is one of blocks of the batch.
+func checkProofBatch(bundle types.ProofBundle) error {
+	for _, data := range bundle.Batch {
+		if sameData(data, bundle.Record) {
+			return nil
+		}
+	}
+	return errors.New("the record is not found in the batch")
+}
+
+// The transaction is the successful transaction of the bundle.
+func checkProofTxResult(bundle types.ProofBundle, txRes proofTxResult) error {
+	switch {
+	case txRes.TxHash != bundle.TxHash:
+		return errors.New("the hash of the tx is " + txRes.TxHash)
+	case txRes.Height != bundle.PublicHeight:
+		return errors.New("the height of the tx is " + txRes.Height)
+	case txRes.Code != 0:
+		return errors.New("the tx is failed, code=" + strconv.Itoa(txRes.Code))
+	}
+	return nil
+}
+
+// The raw transaction is the transaction of the tx hash.
+// Return the decoded transaction.
+func checkProofTxBytes(bundle types.ProofBundle) (proofTx, error) {
+	txbytes, err := base64.StdEncoding.DecodeString(bundle.TxBytes)
+	if err != nil {
+		return proofTx{}, err
+	}
+	if len(txbytes) == 0 {
+		return proofTx{}, errors.New("the bundle has no raw tx")
+	}
+
+	hash := txHash(txbytes)
+	if hash != strings.ToUpper(bundle.TxHash) {
+		return proofTx{}, errors.New("the hash of the raw tx is " + hash)
+	}
+
+	return decodeProofTx(txbytes)
+}
+
+// Decode messages and the memo of the raw transaction.
+// The codec of the XPLA client does not register messages of the wasm, so messages are decoded by their type URLs.
+func decodeProofTx(txbytes []byte) (proofTx, error) {
+	var tx proofTx
+
+	var txRaw txtypes.TxRaw
+	err := txRaw.Unmarshal(txbytes)
+	if err != nil {
+		return tx, err
+	}
+
+	var body txtypes.TxBody
+	err = body.Unmarshal(txRaw.BodyBytes)
+	if err != nil {
+		return tx, err
+	}
+
+	msgs, err := decodeProofTxMsgs(body.Messages)
+	if err != nil {
+		return tx, err
+	}
+
+	tx.Body.Messages = msgs
+	tx.Body.Memo = body.Memo
+
+	return tx, nil
+}
+
+// Other messages are kept as empty, because they do not anchor blocks.
+func decodeProofTxMsgs(anys []*codectypes.Any) ([]proofTxMsg, error) {
+	var msgs []proofTxMsg
+
+	for _, msgAny := range anys {
+		var msg proofTxMsg
+
+		switch msgAny.TypeUrl {
+		case sdk.MsgTypeURL(&wasmtypes.MsgExecuteContract{}):
+			var execMsg wasmtypes.MsgExecuteContract
+			err := execMsg.Unmarshal(msgAny.Value)
+			if err != nil {
+				return nil, err
+			}
+			msg.Contract = execMsg.Contract
+			msg.Msg = json.RawMessage(execMsg.Msg)
+
+		case sdk.MsgTypeURL(&authz.MsgExec{}):
+			var execMsg authz.MsgExec
+			err := execMsg.Unmarshal(msgAny.Value)
+			if err != nil {
+				return nil, err
+			}
+			msg.Msgs, err = decodeProofTxMsgs(execMsg.Msgs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+// The transaction anchors the batch.
+// The memo is the commitment of the batch in the memo sink, otherwise the message to the contract has the batch.
+func checkProofAnchoring(bundle types.ProofBundle, tx proofTx) error {
+	if bundle.Sink == sinkMemo {
+		commitment, _, err := MemoCommitment(bundle.PrivateChainID, bundle.Batch)
+		if err != nil {
+			return err
+		}
+		if tx.Body.Memo != commitment {
+			return errors.New("the memo of the tx is not matched, memo=" + tx.Body.Memo + ", commitment=" + commitment)
+		}
+		return nil
+	}
+
+	anchored := anchoredData(tx.Body.Messages, bundle.ContractAddress)
+	if len(anchored) != len(bundle.Batch) {
+		return errors.New("the tx anchors " + strconv.Itoa(len(anchored)) + " blocks, but the batch has " + strconv.Itoa(len(bundle.Batch)))
+	}
+	for i := range anchored {
+		if !sameData(anchored[i], bundle.Batch[i]) {
+			return errors.New("the block of height " + anchored[i].Height + " is not matched with the batch")
+		}
+	}
+
+	return nil
+}
+
+// Get blocks which are anchored to the contract by messages of the transaction.
+func anchoredData(msgs []proofTxMsg, contractAddr string) []types.Data {
+	var data []types.Data
+
+	for _, msg := range msgs {
+		data = append(data, anchoredData(msg.Msgs, contractAddr)...)
+
+		if msg.Contract != contractAddr || len(msg.Msg) == 0 {
+			continue
+		}
+
+		execMsg := []byte(msg.Msg)
+
+		// The message can be encoded by base64 in some responses.
+		var encoded string
+		if json.Unmarshal(execMsg, &encoded) == nil {
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				continue
+			}
+			execMsg = decoded
+		}
+
+		var anchoring struct {
+			Anchoring *types.Anchoring `json:"anchoring"`
+		}
+		if json.Unmarshal(execMsg, &anchoring) != nil || anchoring.Anchoring == nil {
+			continue
+		}
+
+		data = append(data, anchoring.Anchoring.Data...)
+	}
+
+	return data
+}
+
+// Compare the block info by the normalized encoding.
+func sameData(a, b types.Data) bool {
+	return NormalizeData(a) == NormalizeData(b)
+}
+
+func queryProofTx(xplac *client.XplaClient, txHash string) (string, error) {
+	return xplac.Tx(xtypes.QueryTxMsg{Value: txHash}).Query()
+}
+
+// Request the LCD of the public chain by the query which the XPLA client does not support.
+// Errors are made in the same way as the XPLA client, so the failed endpoint is failed over.
+func queryPubLcd(lcd, path string, query url.Values) ([]byte, error) {
+	lcdUrl := strings.TrimSuffix(lcd, "/") + path
+	if len(query) != 0 {
+		lcdUrl = lcdUrl + "?" + query.Encode()
+	}
+
+	response, err := PubHttpClient().Get(lcdUrl)
+	if err != nil {
+		return nil, errors.New("failed GET method, " + err.Error())
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.New("failed to read response, " + err.Error())
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP request error - [%d : %s]", response.StatusCode, responseBody)
+	}
+
+	return responseBody, nil
+}
@@ -0,0 +1,326 @@
+package gw
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+)
+
+const testSenderAddr = "xpla1qh5jqstdvndyrdmgz3a5l26ppqa50h0xlamn9z"
+
+// Make the raw transaction which anchors the batch.
+// The message is wrapped in MsgExec in the authz mode, and the memo sink sends the commitment by the memo.
+func newTestAnchoringTx(t *testing.T, contractAddr string, batch []types.Data, wrap bool, memo string) ([]byte, string) {
+	t.Helper()
+
+	var msgs []*codectypes.Any
+	if contractAddr != "" {
+		execMsg, err := json.Marshal(struct {
+			Anchoring types.Anchoring `json:"anchoring"`
+		}{types.NewAncoring(batch, batch[len(batch)-1].Height)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var msg sdk.Msg = &wasmtypes.MsgExecuteContract{
+			Sender:   testSenderAddr,
+			Contract: contractAddr,
+			Msg:      wasmtypes.RawContractMessage(execMsg),
+		}
+		if wrap {
+			execMsg := authz.NewMsgExec(sdk.AccAddress(testSenderAddr), []sdk.Msg{msg})
+			msg = &execMsg
+		}
+
+		msgAny, err := codectypes.NewAnyWithValue(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msgAny)
+	}
+
+	body := txtypes.TxBody{Messages: msgs, Memo: memo}
+	bodyBytes, err := body.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txRaw := txtypes.TxRaw{BodyBytes: bodyBytes, AuthInfoBytes: []byte{}, Signatures: [][]byte{[]byte("signature")}}
+	txbytes, err := txRaw.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return txbytes, txHash(txbytes)
+}
+
+// The response of the transaction query which claims the batch.
+func newTestProofTxRes(t *testing.T, contractAddr string, batch []types.Data, hash string) json.RawMessage {
+	t.Helper()
+
+	execMsg, err := json.Marshal(struct {
+		Anchoring types.Anchoring `json:"anchoring"`
+	}{types.NewAncoring(batch, batch[len(batch)-1].Height)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return json.RawMessage(`{"tx":{"body":{"messages":[{"contract":"` + contractAddr + `","msg":` + string(execMsg) + `}],"memo":""}},` +
+		`"tx_response":{"height":"10","txhash":"` + hash + `","code":0}}`)
+}
+
+func newTestProofBundle(t *testing.T, env *testEnv) types.ProofBundle {
+	t.Helper()
+
+	err := InitBlockAdapter(testBlockApi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batch []types.Data
+	for _, block := range env.priv.blocks[3:6] {
+		header, err := ParseBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch = append(batch, types.NewData(header.Height, header.Hash, header.DataHash, header.Time))
+	}
+
+	header, err := ParseBlock(env.priv.blocks[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txbytes, hash := newTestAnchoringTx(t, env.contractAddr, batch, false, "")
+
+	var bundle types.ProofBundle
+	bundle.Version = ProofBundleVersion
+	bundle.Sink = sinkContract
+	bundle.PrivateChainID = testPrivChainID
+	bundle.PublicChainID = testPubChainID
+	bundle.ContractAddress = env.contractAddr
+	bundle.Header = header
+	bundle.Record = batch[1]
+	bundle.Batch = batch
+	bundle.TxHash = hash
+	bundle.PublicHeight = "10"
+	bundle.Tx = newTestProofTxRes(t, env.contractAddr, batch, hash)
+	bundle.TxBytes = base64.StdEncoding.EncodeToString(txbytes)
+
+	return bundle
+}
+
+// The offline verification checks the raw transaction of the tx hash, and the result is not authenticated.
+func TestVerifyProofBundle(t *testing.T) {
+	env := startTestEnv(t)
+
+	forgedBatch := []types.Data{types.NewData("4", "FORGED", "FORGED", "2023-01-01T00:00:04Z")}
+
+	testCases := []struct {
+		name   string
+		modify func(bundle *types.ProofBundle)
+		failed string
+	}{
+		{
+			name:   "valid",
+			modify: func(bundle *types.ProofBundle) {},
+		},
+		{
+			name: "authz",
+			modify: func(bundle *types.ProofBundle) {
+				txbytes, hash := newTestAnchoringTx(t, env.contractAddr, bundle.Batch, true, "")
+				bundle.TxHash = hash
+				bundle.Tx = newTestProofTxRes(t, env.contractAddr, bundle.Batch, hash)
+				bundle.TxBytes = base64.StdEncoding.EncodeToString(txbytes)
+			},
+		},
+		{
+			name: "memo",
+			modify: func(bundle *types.ProofBundle) {
+				commitment, _, err := MemoCommitment(testPrivChainID, bundle.Batch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				txbytes, hash := newTestAnchoringTx(t, "", nil, false, commitment)
+				bundle.Sink = sinkMemo
+				bundle.ContractAddress = ""
+				bundle.TxHash = hash
+				bundle.Tx = json.RawMessage(`{"tx":{"body":{"messages":[],"memo":"` + commitment + `"}},"tx_response":{"height":"10","txhash":"` + hash + `","code":0}}`)
+				bundle.TxBytes = base64.StdEncoding.EncodeToString(txbytes)
+			},
+		},
+		{
+			name: "version 1",
+			modify: func(bundle *types.ProofBundle) {
+				bundle.Version = 1
+			},
+			failed: ProofCheckVersion,
+		},
+		{
+			name: "no raw tx",
+			modify: func(bundle *types.ProofBundle) {
+				bundle.TxBytes = ""
+			},
+			failed: ProofCheckTxBytes,
+		},
+		{
+			name: "raw tx of another hash",
+			modify: func(bundle *types.ProofBundle) {
+				txbytes, _ := newTestAnchoringTx(t, env.contractAddr, forgedBatch, false, "")
+				bundle.TxBytes = base64.StdEncoding.EncodeToString(txbytes)
+			},
+			failed: ProofCheckTxBytes,
+		},
+		{
+			// The response of the transaction claims the batch, but the raw transaction of the hash anchors another batch.
+			name: "forged tx",
+			modify: func(bundle *types.ProofBundle) {
+				txbytes, hash := newTestAnchoringTx(t, env.contractAddr, forgedBatch, false, "")
+				bundle.TxHash = hash
+				bundle.Tx = newTestProofTxRes(t, env.contractAddr, bundle.Batch, hash)
+				bundle.TxBytes = base64.StdEncoding.EncodeToString(txbytes)
+			},
+			failed: ProofCheckAnchoring,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle := newTestProofBundle(t, env)
+			tc.modify(&bundle)
+
+			result := VerifyProofBundle(bundle)
+			if result.Authenticated {
+				t.Fatal("the offline result is authenticated")
+			}
+
+			var failed []string
+			for _, check := range result.Checks {
+				if !check.Passed {
+					failed = append(failed, check.Name)
+				}
+			}
+
+			if tc.failed == "" {
+				if !result.Valid {
+					t.Fatalf("failed checks = %v, want none, checks = %+v", failed, result.Checks)
+				}
+				return
+			}
+			if result.Valid || len(failed) != 1 || failed[0] != tc.failed {
+				t.Fatalf("failed checks = %v, want [%s]", failed, tc.failed)
+			}
+		})
+	}
+}
+
+// Anchoring transactions are searched from the latest one, and the search stops at the first match.
+func TestFindAnchoringTx(t *testing.T) {
+	const batches = 250
+
+	env := startTestEnv(t)
+	env.pub.reset()
+
+	testBatch := func(i int) []types.Data {
+		var batch []types.Data
+		for height := i*testCollectCount - testCollectCount + 1; height <= i*testCollectCount; height++ {
+			h := strconv.Itoa(height)
+			batch = append(batch, types.NewData(h, "HASH"+h, "MERKLE"+h, "2023-01-01T00:00:00Z"))
+		}
+		return batch
+	}
+
+	// Each transaction has its own memo like its own sequence, so the batch which is anchored again has another hash.
+	var delivered int
+	deliver := func(i int, code int) string {
+		delivered++
+		execMsg, err := json.Marshal(struct {
+			Anchoring types.Anchoring `json:"anchoring"`
+		}{types.NewAncoring(testBatch(i), "")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		txbytes, hash := newTestAnchoringTx(t, env.contractAddr, testBatch(i), false, "tx-"+strconv.Itoa(delivered))
+		env.pub.mu.Lock()
+		env.pub.deliver(hash, testPubTx{code: code, sender: testSenderAddr, txBytes: txbytes, contract: env.contractAddr, msg: execMsg})
+		env.pub.mu.Unlock()
+		return hash
+	}
+
+	hashes := make(map[int]string)
+	for i := 1; i <= batches; i++ {
+		hashes[i] = deliver(i, 0)
+	}
+	// The batch is anchored again, and the latest one is found.
+	hashes[5] = deliver(5, 0)
+	// The failed transaction is skipped.
+	deliver(batches-1, testTxFailedCode)
+
+	testCases := []struct {
+		name     string
+		record   types.Data
+		hash     string
+		searches int
+	}{
+		{"latest batch", testBatch(batches)[1], hashes[batches], 1},
+		{"anchored again", testBatch(5)[0], hashes[5], 1},
+		{"old batch", testBatch(2)[2], hashes[2], 3},
+		{"not anchored", testBatch(batches + 1)[0], "", 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env.pub.mu.Lock()
+			env.pub.searches = 0
+			env.pub.mu.Unlock()
+
+			hash, err := FindAnchoringTx(env.pubClient, env.contractAddr, tc.record)
+			if tc.hash == "" {
+				if err == nil || !strings.Contains(err.Error(), "is not found") {
+					t.Fatalf("err = %v, want not found", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hash != tc.hash {
+					t.Fatalf("hash = %s, want %s", hash, tc.hash)
+				}
+			}
+
+			env.pub.mu.Lock()
+			searches := env.pub.searches
+			env.pub.mu.Unlock()
+			if searches != tc.searches {
+				t.Fatalf("searches = %d, want %d", searches, tc.searches)
+			}
+		})
+	}
+
+	// The raw transaction is found in the public block.
+	env.pub.mu.Lock()
+	height := strconv.Itoa(env.pub.txs[hashes[2]].height)
+	env.pub.mu.Unlock()
+
+	txBytes, err := queryPubTxBytes(env.pubClient, height, hashes[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	txbytes, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txHash(txbytes) != hashes[2] {
+		t.Fatalf("hash of the raw tx = %s, want %s", txHash(txbytes), hashes[2])
+	}
+}
@@ -0,0 +1,195 @@
+package gw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+)
+
+const txInMempoolErr = "tx already exists in cache"
+
+// Errors of the XPLA client which are caused by the endpoint, not by the request.
+var endpointErrs = []string{
+	"failed GET method",
+	"failed POST method",
+	"failed to read response",
+	"- [429 ",
+	"- [502 ",
+	"- [503 ",
+	"- [504 ",
+}
+
+// The XPLA client writes the response of the broadcast to the global variable,
+// so senders of several accounts broadcast one by one.
+var broadcastMu sync.Mutex
+
+var pubEndpointInstance *PubEndpoint
+var pubEndpointOnce sync.Once
+
+// Manage LCD endpoints of the public chain.
+// The current endpoint is used for queries and broadcasts until it fails,
+// and then the next endpoint in the configured order is used.
+type PubEndpoint struct {
+	mu        sync.Mutex
+	endpoints []string
+	current   int
+}
+
+func PubEndpointMng() *PubEndpoint {
+	pubEndpointOnce.Do(func() {
+		pubConf := app.AppFile().Get().Config.PublicChain
+		pubEndpointInstance = &PubEndpoint{
+			endpoints: uniqueLCDs(pubConf.LCD, pubConf.LCDs),
+		}
+	})
+	return pubEndpointInstance
+}
+
+// Get LCD URLs without empty and duplicated URLs.
+// The LCD is the primary endpoint, and LCDs are the fallback endpoints.
+func uniqueLCDs(primary string, others []string) []string {
+	var lcds []string
+	seen := make(map[string]bool)
+
+	for _, lcd := range append([]string{primary}, others...) {
+		lcd = strings.TrimSuffix(lcd, "/")
+		if lcd == "" || seen[lcd] {
+			continue
+		}
+		seen[lcd] = true
+		lcds = append(lcds, lcd)
+	}
+
+	return lcds
+}
+
+// Change endpoints by the reloaded config.
+// The current endpoint is kept if it is still in endpoints.
+func (p *PubEndpoint) setEndpoints(endpoints []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := p.endpoints[p.current]
+	p.endpoints = endpoints
+	p.current = 0
+	for i, endpoint := range endpoints {
+		if endpoint == current {
+			p.current = i
+		}
+	}
+}
+
+func (p *PubEndpoint) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.endpoints)
+}
+
+func (p *PubEndpoint) Current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.endpoints[p.current]
+}
+
+// Move to the next endpoint if the failed endpoint is still the current one.
+// Several goroutines can fail by the same endpoint at the same time, so the endpoint moves only once.
+func (p *PubEndpoint) failover(failed string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.endpoints) < 2 || p.endpoints[p.current] != failed {
+		return
+	}
+
+	p.current = (p.current + 1) % len(p.endpoints)
+	util.LogWarning("public chain endpoint is failed, fail over to", p.endpoints[p.current], err)
+}
+
+// Run the request of the XPLA client by the current endpoint.
+// If the endpoint fails, the request is sent again by the next endpoint.
+func withPubFailover(xplac *client.XplaClient, request func(*client.XplaClient) error) error {
+	var err error
+
+	for i := 0; i < PubEndpointMng().count(); i++ {
+		endpoint := PubEndpointMng().Current()
+
+		err = request(xplac.WithURL(endpoint))
+		if err == nil || !isEndpointErr(err) {
+			return err
+		}
+
+		PubEndpointMng().failover(endpoint, err)
+	}
+
+	return err
+}
+
+// Broadcast the signed transaction.
+// If the endpoint fails, the transaction may be broadcasted before failing,
+// so check whether the transaction already landed before broadcasting it to the next endpoint.
+// The same signed transaction is broadcasted again, so the batch is never sent twice.
+// If the transaction is sent again after all endpoints failed, set retried to check it at first.
+func broadcastWithFailover(xplac *client.XplaClient, txbytes []byte, txHash string, retried bool) error {
+	var err error
+
+	for i := 0; i < PubEndpointMng().count(); i++ {
+		endpoint := PubEndpointMng().Current()
+		xplac.WithURL(endpoint)
+
+		if retried || i > 0 {
+			_, queryErr := xplac.Tx(xtypes.QueryTxMsg{Value: txHash}).Query()
+			if queryErr == nil {
+				util.LogInfo(util.BB("tx already landed="), txHash)
+				return nil
+			}
+		}
+
+		broadcastMu.Lock()
+		_, err = xplac.Broadcast(txbytes)
+		broadcastMu.Unlock()
+		if err == nil {
+			return nil
+		}
+
+		if (retried || i > 0) && strings.Contains(err.Error(), txInMempoolErr) {
+			util.LogInfo(util.BB("tx already in mempool="), txHash)
+			return nil
+		}
+
+		if !isEndpointErr(err) {
+			return err
+		}
+
+		PubEndpointMng().failover(endpoint, err)
+	}
+
+	if err == nil {
+		err = errors.New("no endpoint of the public chain")
+	}
+
+	return err
+}
+
+func isEndpointErr(err error) bool {
+	for _, endpointErr := range endpointErrs {
+		if strings.Contains(err.Error(), endpointErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the hash of the signed transaction.
+func txHash(txbytes []byte) string {
+	hash := sha256.Sum256(txbytes)
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
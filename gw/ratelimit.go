@@ -0,0 +1,242 @@
+package gw
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const (
+	defaultRateLimitBurst = 10
+	defaultMaxBackoff     = 60000
+	minBackoff            = 1000
+)
+
+var rateLimiterInstance *RateLimiter
+var rateLimiterOnce sync.Once
+
+// Limit requests to the private chain by the token bucket.
+// Tokens are filled by the rate, and the bucket holds tokens up to the burst,
+// so the fetcher catches up quickly after idle time while the private chain is healthy.
+// If the private chain responds 429 or 5xx, the limiter backs off exponentially or by Retry-After.
+type RateLimiter struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	backoff      time.Duration
+	backoffUntil time.Time
+	throttled    uint64
+}
+
+// If the rate is not set, the rate follows the request period in order to keep the previous behavior.
+func RateLimitMng() *RateLimiter {
+	rateLimiterOnce.Do(func() {
+		rate, burst := rateLimitConfig(app.AppFile().Get().Config.Anchor)
+
+		rateLimiterInstance = &RateLimiter{
+			rate:   rate,
+			burst:  burst,
+			tokens: burst,
+			last:   time.Now(),
+		}
+	})
+	return rateLimiterInstance
+}
+
+// Apply the rate and the burst of the reloaded config.
+// Tokens in the bucket are kept up to the new burst.
+func (r *RateLimiter) Reconfigure() {
+	rate, burst := rateLimitConfig(app.AppFile().Get().Config.Anchor)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rate = rate
+	r.burst = burst
+	r.tokens = math.Min(r.tokens, burst)
+}
+
+func rateLimitConfig(anchorConf app.Anchor) (float64, float64) {
+	rate := anchorConf.RateLimit.Rate
+	burst := anchorConf.RateLimit.Burst
+	if rate <= 0 {
+		rate = math.Inf(1)
+		if anchorConf.RequestPeriod > 0 {
+			rate = 1000 / float64(anchorConf.RequestPeriod)
+		}
+		if burst <= 0 {
+			burst = 1
+		}
+	}
+	if burst <= 0 {
+		burst = defaultRateLimitBurst
+	}
+
+	return rate, float64(burst)
+}
+
+// Wait until the token is available and the backoff is over.
+// It returns false if the context is done before it.
+func (r *RateLimiter) Wait(ctx context.Context) bool {
+	for {
+		r.mu.Lock()
+		now := time.Now()
+
+		if now.Before(r.backoffUntil) {
+			wait := r.backoffUntil.Sub(now)
+			r.mu.Unlock()
+			if !sleepContext(ctx, wait) {
+				return false
+			}
+			continue
+		}
+
+		if math.IsInf(r.rate, 1) {
+			r.mu.Unlock()
+			return true
+		}
+
+		r.tokens = math.Min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+		r.last = now
+
+		if r.tokens >= 1 {
+			r.tokens--
+			r.mu.Unlock()
+			return true
+		}
+
+		wait := time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+		r.mu.Unlock()
+		if !sleepContext(ctx, wait) {
+			return false
+		}
+	}
+}
+
+// Back off requests because the private chain is overloaded.
+// Retry-After of the response is used if it exists, otherwise the backoff doubles up to the max backoff.
+// Retry-After is also limited by the max backoff, so the endpoint can not stop the fetcher for a long time.
+func (r *RateLimiter) Throttle(retryAfter time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maxBackoff := maxBackoffDuration()
+
+	if r.backoff == 0 {
+		r.backoff = time.Millisecond * time.Duration(minBackoff)
+	} else {
+		r.backoff *= 2
+	}
+	if r.backoff > maxBackoff {
+		r.backoff = maxBackoff
+	}
+
+	wait := r.backoff
+	if retryAfter > 0 {
+		wait = retryAfter
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+
+	r.throttled++
+	r.tokens = 0
+	r.backoffUntil = time.Now().Add(wait)
+
+	util.LogWarning("private chain is overloaded, back off requests", util.BB("backoff=")+wait.String(), util.BB("throttled=")+util.FromUint64ToString(r.throttled))
+}
+
+// The max backoff can be changed by reloading the config.
+func maxBackoffDuration() time.Duration {
+	maxBackoff := app.AppFile().Get().Config.Anchor.RateLimit.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	return time.Millisecond * time.Duration(maxBackoff)
+}
+
+// Reset the backoff after the request succeeds.
+func (r *RateLimiter) Recover() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.backoff == 0 {
+		return
+	}
+
+	r.backoff = 0
+	util.LogInfo(util.BB("private chain is recovered, resume requests"))
+}
+
+// The rate is zero if requests are not limited.
+func (r *RateLimiter) Status() types.RateLimitStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rate := r.rate
+	if math.IsInf(rate, 1) {
+		rate = 0
+	}
+
+	state := types.RateLimitStateOk
+	if time.Now().Before(r.backoffUntil) {
+		state = types.RateLimitStateBackoff
+	}
+
+	return types.NewRateLimitStatus(state, rate, r.burst, r.tokens, r.backoff.Milliseconds(), r.throttled)
+}
+
+// The private chain responds 429 or 5xx.
+type RequestStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+}
+
+func NewRequestStatusError(statusCode int, retryAfter time.Duration, body string) *RequestStatusError {
+	return &RequestStatusError{
+		StatusCode: statusCode,
+		RetryAfter: retryAfter,
+		Body:       body,
+	}
+}
+
+func (e *RequestStatusError) Error() string {
+	return "status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// Get the waiting time of the Retry-After header.
+// The header is seconds or the HTTP date, and the waiting time is limited by the max backoff.
+func parseRetryAfter(retryAfter string) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+
+	maxBackoff := maxBackoffDuration()
+
+	if seconds, err := strconv.ParseInt(retryAfter, 10, 64); err == nil && seconds > 0 {
+		if seconds > int64(maxBackoff/time.Second) {
+			return maxBackoff
+		}
+		return time.Second * time.Duration(seconds)
+	}
+
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		wait := time.Until(date)
+		if wait > maxBackoff {
+			return maxBackoff
+		}
+		return wait
+	}
+
+	return 0
+}
@@ -0,0 +1,43 @@
+package gw
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+)
+
+// Retry-After which is longer than the max backoff waits for the max backoff.
+func TestRetryAfterLimitedByMaxBackoff(t *testing.T) {
+	env := startTestEnv(t)
+
+	const maxBackoff = 2000
+	env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.RateLimit.MaxBackoff = maxBackoff
+	})
+	defer env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.RateLimit.MaxBackoff = 0
+	})
+
+	for _, tc := range []struct {
+		retryAfter string
+		want       time.Duration
+	}{
+		{"1", time.Second},
+		{"86400", time.Millisecond * maxBackoff},
+		{strconv.FormatInt(1<<62, 10), time.Millisecond * maxBackoff},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), time.Millisecond * maxBackoff},
+	} {
+		if got := parseRetryAfter(tc.retryAfter); got != tc.want {
+			t.Fatalf("Retry-After %s = %s, want %s", tc.retryAfter, got, tc.want)
+		}
+	}
+
+	var limiter RateLimiter
+	limiter.Throttle(time.Hour)
+	if wait := time.Until(limiter.backoffUntil); wait > time.Millisecond*maxBackoff {
+		t.Fatalf("the limiter backs off for %s, want %dms at most", wait, maxBackoff)
+	}
+}
@@ -0,0 +1,70 @@
+package gw
+
+import (
+	"os"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const appFileWatchPeriod = 2000
+
+// Reload app.yaml while the gateway runs.
+// Safe fields are applied from the next batch, and the file which changes fields that need the restart is rejected.
+func ReloadAppFile(appFilePath, source string) {
+	err := app.AppFile().Reload(appFilePath)
+	if err != nil {
+		util.LogWarning("app.yaml is not reloaded, the current config is kept:", err)
+		return
+	}
+
+	conf := app.AppFile().Get().Config
+
+	RateLimitMng().Reconfigure()
+	PubEndpointMng().setEndpoints(uniqueLCDs(conf.PublicChain.LCD, conf.PublicChain.LCDs))
+	PrivEndpointMng().setEndpoints(uniqueLCDs(conf.PrivateChain.LCD, conf.PrivateChain.LCDs))
+
+	// Transports of new LCDs are added.
+	err = InitChainTransports(conf)
+	if err != nil {
+		util.LogWarning("failed to apply transports of reloaded LCDs:", err)
+	}
+
+	util.LogInfo(util.BB("app.yaml is reloaded by ") + source)
+}
+
+// Reload app.yaml when the file is changed.
+// The file is reloaded after its modified time is not changed for a period, so the file which is being written is not read.
+func WatchAppFile(appFilePath string) {
+	lastModTime := appFileModTime(appFilePath)
+	pending := false
+
+	for {
+		time.Sleep(time.Millisecond * time.Duration(appFileWatchPeriod))
+
+		modTime := appFileModTime(appFilePath)
+		if modTime.IsZero() {
+			continue
+		}
+
+		if !modTime.Equal(lastModTime) {
+			lastModTime = modTime
+			pending = true
+			continue
+		}
+
+		if pending {
+			pending = false
+			ReloadAppFile(appFilePath, "file change")
+		}
+	}
+}
+
+func appFileModTime(appFilePath string) time.Time {
+	info, err := os.Stat(appFilePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,210 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+)
+
+const (
+	defaultMaxRestarts   = 5
+	defaultRestartWindow = 600000
+	minRestartBackoff    = 1000
+	maxRestartBackoff    = 30000
+)
+
+// The error of the component of the gateway.
+// The panic of the component is recovered to it with the stack.
+type ComponentError struct {
+	Component string
+	Err       error
+	Stack     string
+}
+
+func (e *ComponentError) Error() string {
+	return e.Component + ": " + e.Err.Error()
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// The supervisor owns goroutines of components of the gateway.
+// If the component panics or returns the error, it is restarted after the backoff.
+// If components are restarted more than the max restarts in the window, the supervisor stops all of them.
+// Each component receives the context of the supervisor, and it returns when the context is done.
+type Supervisor struct {
+	mu          sync.Mutex
+	maxRestarts int
+	window      time.Duration
+	restarts    []time.Time
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	err         error
+}
+
+// The supervisor is stopped when the parent context is done.
+func NewSupervisor(parent context.Context) *Supervisor {
+	supervisorConf := app.AppFile().Get().Config.Anchor.Supervisor
+
+	maxRestarts := supervisorConf.MaxRestarts
+	if maxRestarts <= 0 {
+		maxRestarts = defaultMaxRestarts
+	}
+
+	window := supervisorConf.RestartWindow
+	if window <= 0 {
+		window = defaultRestartWindow
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+
+	return &Supervisor{
+		maxRestarts: maxRestarts,
+		window:      time.Millisecond * time.Duration(window),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Run the component in the goroutine.
+// The component which returns nil is finished and not restarted.
+func (s *Supervisor) Go(name string, run func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		backoff := time.Millisecond * time.Duration(minRestartBackoff)
+
+		for {
+			started := time.Now()
+			err := s.run(name, run)
+			if err == nil || s.stopped() {
+				return
+			}
+
+			util.LogErr(types.ErrSupervisor, err)
+
+			var componentErr *ComponentError
+			if errors.As(err, &componentErr) && componentErr.Stack != "" {
+				util.LogWarning(componentErr.Stack)
+			}
+
+			if !s.allowRestart() {
+				s.stop(errors.New("too many restarts in " + s.window.String() + ", last error=" + err.Error()))
+				return
+			}
+
+			// The component which ran longer than the window is restarted without the backoff of previous failures.
+			if time.Since(started) > s.window {
+				backoff = time.Millisecond * time.Duration(minRestartBackoff)
+			}
+
+			util.LogWait("restart component...", util.BB("component=")+name, util.BB("backoff=")+backoff.String())
+
+			if !sleepContext(s.ctx, backoff) {
+				return
+			}
+
+			backoff *= 2
+			if backoff > time.Millisecond*time.Duration(maxRestartBackoff) {
+				backoff = time.Millisecond * time.Duration(maxRestartBackoff)
+			}
+		}
+	}()
+}
+
+// Stop all components and wait until they return.
+// Components are not restarted after it, and the first error is kept.
+func (s *Supervisor) Stop(err error) {
+	s.stop(err)
+	s.wg.Wait()
+}
+
+// Cancel the context of components without waiting, because it is called by the component.
+func (s *Supervisor) stop(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped() {
+		return
+	}
+
+	s.err = err
+	s.cancel()
+}
+
+// It is closed when the supervisor stops.
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+func (s *Supervisor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *Supervisor) stopped() bool {
+	return s.ctx.Err() != nil
+}
+
+// Record the restart, and check restarts in the window do not exceed the max restarts.
+func (s *Supervisor) allowRestart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var restarts []time.Time
+	for _, restart := range s.restarts {
+		if now.Sub(restart) < s.window {
+			restarts = append(restarts, restart)
+		}
+	}
+	s.restarts = append(restarts, now)
+
+	return len(s.restarts) <= s.maxRestarts
+}
+
+// Run the component, and recover the panic to the error.
+func (s *Supervisor) run(name string, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ComponentError{
+				Component: name,
+				Err:       fmt.Errorf("panic: %v", r),
+				Stack:     string(debug.Stack()),
+			}
+		}
+	}()
+
+	err = run(s.ctx)
+	if err != nil {
+		return &ComponentError{Component: name, Err: err}
+	}
+	return nil
+}
+
+// Sleep for the duration.
+// It returns false if the context is done before the duration.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,323 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/state"
+	"github.com/Moonyongjung/xpla-anchor/types"
+)
+
+// Stop returns after every component returns.
+func TestSupervisorStopWaitsForComponents(t *testing.T) {
+	supervisor := NewSupervisor(context.Background())
+
+	var returned int32
+	for _, name := range []string{"first", "second", "third"} {
+		supervisor.Go(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Millisecond * 50)
+			atomic.AddInt32(&returned, 1)
+			return nil
+		})
+	}
+
+	supervisor.Stop(nil)
+
+	if n := atomic.LoadInt32(&returned); n != 3 {
+		t.Fatalf("returned components = %d, want 3", n)
+	}
+	if supervisor.Err() != nil {
+		t.Fatalf("err = %v, want nil", supervisor.Err())
+	}
+}
+
+// Components stop by the parent context without the error.
+func TestSupervisorParentContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	supervisor := NewSupervisor(parent)
+
+	supervisor.Go("component", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	cancel()
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(time.Second):
+		t.Fatal("supervisor is not stopped by the parent context")
+	}
+
+	supervisor.Stop(nil)
+	if supervisor.Err() != nil {
+		t.Fatalf("err = %v, want nil", supervisor.Err())
+	}
+}
+
+// The component is not restarted while it waits for the backoff after the supervisor stops.
+func TestSupervisorStopDuringBackoff(t *testing.T) {
+	supervisor := NewSupervisor(context.Background())
+
+	var runs int32
+	supervisor.Go("component", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("failed")
+	})
+
+	time.Sleep(time.Millisecond * 100)
+
+	stopped := make(chan struct{})
+	go func() {
+		supervisor.Stop(errors.New("stopped"))
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Millisecond * minRestartBackoff / 2):
+		t.Fatal("stop waits for the backoff")
+	}
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("runs = %d, want 1", n)
+	}
+	if supervisor.Err() == nil || supervisor.Err().Error() != "stopped" {
+		t.Fatalf("err = %v, want stopped", supervisor.Err())
+	}
+}
+
+// Senders are stopped when the entry point returns, so the batch which is queued after it is never broadcasted.
+func assertSendersStopped(t *testing.T, env *testEnv, a *types.App) {
+	t.Helper()
+
+	env.pub.mu.Lock()
+	delivered := env.pub.delivered
+	env.pub.mu.Unlock()
+
+	select {
+	case a.Channels.AnchringTx <- types.NewAncoring([]types.Data{types.NewData("1", env.priv.hashes[0], "", "")}, "1"):
+	default:
+		t.Fatal("the queue of batches is full")
+	}
+
+	time.Sleep(time.Millisecond * 500)
+
+	env.pub.mu.Lock()
+	defer env.pub.mu.Unlock()
+
+	if env.pub.delivered != delivered {
+		t.Fatalf("delivered txs after the return = %d, want %d", env.pub.delivered-delivered, 0)
+	}
+	if env.pub.mismatched != 0 {
+		t.Fatalf("sequence mismatches = %d, want 0", env.pub.mismatched)
+	}
+}
+
+func TestStartRepairGapsStopsComponents(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	err := StartRepairGaps(a, env.contractAddr, testBlockApi, []uint64{2, 5, 6, 7, 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertSendersStopped(t, env, a)
+}
+
+func TestStartBackfillStopsComponents(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	progressFile := filepath.Join(t.TempDir(), "backfill.json")
+	err := StartBackfill(context.Background(), a, env.contractAddr, testBlockApi, "3", "10", progressFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progress, err := loadBackfillProgress(progressFile, "3", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.ConfirmedHeight != "10" {
+		t.Fatalf("confirmed height = %s, want 10", progress.ConfirmedHeight)
+	}
+
+	assertSendersStopped(t, env, a)
+}
+
+// The canceled backfill stops components without finishing the range, so it is resumed by the next run.
+func TestStartBackfillCanceled(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	progressFile := filepath.Join(t.TempDir(), "backfill.json")
+	err := StartBackfill(ctx, a, env.contractAddr, testBlockApi, "3", "10", progressFile)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+
+	progress, err := loadBackfillProgress(progressFile, "3", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.ConfirmedHeight == "10" {
+		t.Fatal("the canceled backfill is finished")
+	}
+
+	assertSendersStopped(t, env, a)
+}
+
+// The leader which loses the lease returns after the gateway is stopped.
+func TestStartHALeaseLoss(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	lockFile := filepath.Join(t.TempDir(), "anchor.lock")
+	env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.HA = app.HA{
+			Backend:       haBackendFile,
+			LockFile:      lockFile,
+			LeaseDuration: 2000,
+			RenewPeriod:   300,
+			InstanceID:    "leader",
+		}
+	})
+	defer env.setConfig(t, func(appType *app.AppType) {
+		appType.Config.Anchor.HA = app.HA{}
+	})
+
+	haErr := make(chan error, 1)
+	go func() {
+		haErr <- StartHA(context.Background(), a, env.contractAddr, testBlockApi, "")
+	}()
+
+	// The lease is renewed only by the leader.
+	lease := &fileLease{path: lockFile}
+	var acquired int64
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		record, err := lease.read()
+		if err == nil && record.Owner == "leader" {
+			if acquired != 0 && record.ExpiresAt != acquired {
+				break
+			}
+			acquired = record.ExpiresAt
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the instance does not become the leader")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	// Another instance takes the lease.
+	err := lease.write(fileLeaseRecord{Owner: "other", ExpiresAt: time.Now().Add(time.Hour).UnixMilli()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-haErr:
+		if err == nil || !strings.Contains(err.Error(), "the leader lease is lost") {
+			t.Fatalf("err = %v, want the lost lease", err)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("the leader does not return after the lease is lost")
+	}
+
+	assertSendersStopped(t, env, a)
+}
+
+// The sender which hands over the broadcasted tx to the full queue returns when the supervisor stops,
+// and the next run confirms the tx which is kept in the state.
+func TestSenderStopsWithFullPendingTx(t *testing.T) {
+	env := startTestEnv(t)
+	a := env.newApp(t)
+
+	for hash := range state.Mng().PendingTxs() {
+		state.Mng().RemovePendingTx(hash)
+	}
+
+	// Nothing takes pending txs.
+	for i := 0; i < cap(a.Channels.PendingTx); i++ {
+		a.Channels.PendingTx <- types.NewPendingTx(types.Anchoring{}, "", "", "FULL"+strconv.Itoa(i))
+	}
+
+	env.pub.mu.Lock()
+	delivered := env.pub.delivered
+	env.pub.mu.Unlock()
+
+	a.Channels.AnchringTx <- types.NewAncoring([]types.Data{types.NewData("1", env.priv.hashes[0], "", "")}, "1")
+
+	supervisor := NewSupervisor(context.Background())
+	supervisor.Go("sender-0", SendAnchoringTx(a, a.Senders[0], ""))
+
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		env.pub.mu.Lock()
+		broadcasted := env.pub.delivered > delivered
+		env.pub.mu.Unlock()
+		if broadcasted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the batch is not broadcasted")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		supervisor.Stop(nil)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second * 5):
+		t.Fatal("stop waits for the sender which hands over the pending tx")
+	}
+
+	restored := restorePendingTxs()
+	if len(restored) != 1 || restored[0].Anchoring.Data[0].Height != "1" {
+		t.Fatalf("restored pending txs = %+v, want the tx of the height 1", restored)
+	}
+
+	// The tx of the previous run is confirmed, or its batch is sent again if the tx is failed.
+	next := env.newApp(t)
+	next.Channels.ConfirmedTx = make(chan types.Anchoring, 1)
+
+	supervisor = NewSupervisor(context.Background())
+	defer supervisor.Stop(nil)
+	supervisor.Go("confirmer", confirmAnchoringTx(next, restored))
+
+	select {
+	case anchoring := <-next.Channels.ConfirmedTx:
+		if anchoring.Data[0].Height != "1" {
+			t.Fatalf("confirmed height = %s, want 1", anchoring.Data[0].Height)
+		}
+	case anchoring := <-next.Channels.RetryTx:
+		if anchoring.Data[0].Height != "1" {
+			t.Fatalf("retried height = %s, want 1", anchoring.Data[0].Height)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("the restored pending tx is not confirmed")
+	}
+
+	supervisor.Stop(nil)
+	if pendingTxs := state.Mng().PendingTxs(); len(pendingTxs) != 0 {
+		t.Fatalf("pending txs in the state = %d, want 0", len(pendingTxs))
+	}
+}
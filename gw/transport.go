@@ -0,0 +1,431 @@
+package gw
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+)
+
+const httpClientTimeout = 30
+
+var privHttpClientInstance *http.Client
+var privHttpClientOnce sync.Once
+var privHttpClientErr error
+
+// The HTTP client to request the private chain.
+// The client is made once, so connections are reused by requests.
+func PrivHttpClient() (*http.Client, error) {
+	privHttpClientOnce.Do(func() {
+		roundTripper, err := NewRoundTripper(app.AppFile().Get().Config.PrivateChain.Transport)
+		if err != nil {
+			privHttpClientErr = err
+			return
+		}
+
+		privHttpClientInstance = &http.Client{
+			Transport: roundTripper,
+			Timeout:   time.Second * httpClientTimeout,
+		}
+	})
+	return privHttpClientInstance, privHttpClientErr
+}
+
+var pubHttpClientInstance *http.Client
+var pubHttpClientOnce sync.Once
+
+// The HTTP client to request the public chain by queries which the XPLA client does not support.
+// It uses the default transport, so transport settings of the public chain are applied as the XPLA client.
+func PubHttpClient() *http.Client {
+	pubHttpClientOnce.Do(func() {
+		pubHttpClientInstance = &http.Client{
+			Timeout: time.Second * httpClientTimeout,
+		}
+	})
+	return pubHttpClientInstance
+}
+
+// Apply transport settings of LCDs of both chains to the XPLA client.
+func InitChainTransports(conf app.ConfigType) error {
+	transports := make(map[string]app.Transport)
+	for _, pubLcd := range uniqueLCDs(conf.PublicChain.LCD, conf.PublicChain.LCDs) {
+		transports[pubLcd] = conf.PublicChain.Transport
+	}
+	for _, privLcd := range uniqueLCDs(conf.PrivateChain.LCD, conf.PrivateChain.LCDs) {
+		transports[privLcd] = conf.PrivateChain.Transport
+	}
+
+	// The authorization header is only added by the gateway's own client which requests blocks of the private chain.
+	// Requests of the public chain are sent by the XPLA client with the shared default transport which can not add the header,
+	// so the gateway does not start if the authorization is set.
+	if hasAuth(conf.PublicChain.Transport) {
+		return errors.New("the bearer token and the basic auth of the public chain transport are not supported, requests of the XPLA client can not add the authorization header")
+	}
+
+	return InitDefaultTransport(transports)
+}
+
+// Apply transport settings to the XPLA client.
+// The XPLA client makes the HTTP client with the default transport for each request,
+// so the default transport is configured to select the proxy and TLS settings by the host of the request.
+// The default transport is kept as *http.Transport, because libraries such as the EVM client of xpla.go assert it.
+// If it is already configured, hosts are updated in place, because requests can be in flight.
+func InitDefaultTransport(transports map[string]app.Transport) error {
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return errors.New("the default transport of the net/http is not *http.Transport")
+	}
+
+	// The CA bundle which is shared by LCDs is read once.
+	rootCAs := make(map[string]*x509.CertPool)
+
+	hosts := make(map[string]hostTransport)
+	for lcd, transport := range transports {
+		lcdUrl, err := url.Parse(lcd)
+		if err != nil {
+			return err
+		}
+
+		hostTransport, err := newHostTransport(transport, rootCAs)
+		if err != nil {
+			return err
+		}
+		hosts[hostPort(lcdUrl)] = hostTransport
+	}
+
+	defaultHosts.set(hosts)
+	defaultHostsOnce.Do(func() {
+		configureDefaultTransport(defaultTransport)
+	})
+
+	return nil
+}
+
+// Make the round tripper of the gateway's own HTTP client by transport settings.
+// TLS verification is on unless it is disabled explicitly, and the authorization header is added.
+func NewRoundTripper(transport app.Transport) (http.RoundTripper, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: transport.InsecureSkipVerify,
+	}
+
+	if transport.CAFile != "" {
+		rootCAs, err := loadRootCAs(transport.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	// Client certificates for mTLS.
+	if transport.CertFile != "" || transport.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(transport.CertFile, transport.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("the default transport of the net/http is not *http.Transport")
+	}
+
+	// The gateway's own client does not use settings of hosts of the default transport.
+	httpTransport := defaultTransport.Clone()
+	httpTransport.TLSClientConfig = tlsConfig
+	httpTransport.Proxy = http.ProxyFromEnvironment
+
+	if transport.Proxy != "" {
+		proxyUrl, err := url.Parse(transport.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		httpTransport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if transport.BearerToken != "" && transport.BasicAuth.UserName != "" {
+		return nil, errors.New("select one of the bearer token and the basic auth")
+	}
+
+	return &authRoundTripper{
+		base:      httpTransport,
+		transport: transport,
+	}, nil
+}
+
+// Add the authorization header to requests.
+type authRoundTripper struct {
+	base      http.RoundTripper
+	transport app.Transport
+}
+
+func (t *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !hasAuth(t.transport) {
+		return t.base.RoundTrip(req)
+	}
+
+	// The round tripper must not modify the request.
+	req = req.Clone(req.Context())
+	if t.transport.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+t.transport.BearerToken)
+	} else {
+		req.SetBasicAuth(t.transport.BasicAuth.UserName, t.transport.BasicAuth.Password)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// Settings of the host of the LCD which are applied to the default transport.
+// The authorization header is not added by the default transport, because it is shared by all requests of the process.
+type hostTransport struct {
+	proxy              *url.URL
+	insecureSkipVerify bool
+	certificate        *tls.Certificate
+	// The CA pool which verifies the server of the host. Nil means the system CA pool.
+	rootCAs *x509.CertPool
+}
+
+func newHostTransport(transport app.Transport, rootCAs map[string]*x509.CertPool) (hostTransport, error) {
+	var hostTransport hostTransport
+	hostTransport.insecureSkipVerify = transport.InsecureSkipVerify
+
+	if transport.Proxy != "" {
+		proxyUrl, err := url.Parse(transport.Proxy)
+		if err != nil {
+			return hostTransport, err
+		}
+		hostTransport.proxy = proxyUrl
+	}
+
+	if transport.CertFile != "" || transport.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(transport.CertFile, transport.KeyFile)
+		if err != nil {
+			return hostTransport, err
+		}
+		hostTransport.certificate = &cert
+	}
+
+	if transport.CAFile != "" {
+		pool, ok := rootCAs[transport.CAFile]
+		if !ok {
+			var err error
+			pool, err = loadRootCAs(transport.CAFile)
+			if err != nil {
+				return hostTransport, err
+			}
+			rootCAs[transport.CAFile] = pool
+		}
+		hostTransport.rootCAs = pool
+	}
+
+	return hostTransport, nil
+}
+
+// Hosts of LCDs which are used by the default transport.
+// The proxy is selected by the host with the port. The TLS server name does not have the port,
+// so LCDs of the same host name are found in the order of the port, and every result of the lookup is deterministic.
+type hostTransports struct {
+	mu    sync.RWMutex
+	hosts map[string]hostTransport
+	names map[string][]hostTransport
+	certs []*tls.Certificate
+}
+
+var defaultHosts hostTransports
+var defaultHostsOnce sync.Once
+
+func (h *hostTransports) set(hosts map[string]hostTransport) {
+	var keys []string
+	for key := range hosts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	names := make(map[string][]hostTransport)
+	var certs []*tls.Certificate
+	for _, key := range keys {
+		names[hostName(key)] = append(names[hostName(key)], hosts[key])
+		if hosts[key].certificate != nil {
+			certs = append(certs, hosts[key].certificate)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hosts = hosts
+	h.names = names
+	h.certs = certs
+}
+
+// Find settings by the host with the port.
+func (h *hostTransports) find(hostPort string) (hostTransport, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	hostTransport, ok := h.hosts[hostPort]
+	return hostTransport, ok
+}
+
+// Find settings of LCDs by the host name which is the server name of TLS.
+func (h *hostTransports) findName(name string) []hostTransport {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.names[name]
+}
+
+// Client certificates of all hosts in the order of hosts.
+func (h *hostTransports) certificates() []*tls.Certificate {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.certs
+}
+
+// Configure the default transport once.
+// The proxy is selected by the host, and the environment proxy is used for other hosts.
+// The server certificate is verified by the CA pool of the host unless the host skips verification.
+func configureDefaultTransport(defaultTransport *http.Transport) {
+	envProxy := defaultTransport.Proxy
+	defaultTransport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if hostTransport, ok := defaultHosts.find(hostPort(req.URL)); ok && hostTransport.proxy != nil {
+			return hostTransport.proxy, nil
+		}
+		if envProxy == nil {
+			return nil, nil
+		}
+		return envProxy(req)
+	}
+
+	tlsConfig := defaultTransport.TLSClientConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+
+	// Verification is done by VerifyConnection, because the CA pool and the skip are selected by the host.
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = verifyHostConnection
+	tlsConfig.GetClientCertificate = hostClientCertificate
+	defaultTransport.TLSClientConfig = tlsConfig
+}
+
+// Verify the certificate chain of the server as the default verification of the crypto/tls.
+// The CA bundle of the LCD is only trusted for the host of the LCD, so the CA of the private chain does not verify the public chain.
+// The server name does not have the port, so the server is verified if one of LCDs of the host name verifies it,
+// and verification is skipped only if all of them skip it.
+func verifyHostConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no certificate of the server " + cs.ServerName)
+	}
+
+	// Hosts which are not LCDs are verified by the system CA pool.
+	hostTransports := defaultHosts.findName(cs.ServerName)
+	skip := len(hostTransports) != 0
+
+	var pools []*x509.CertPool
+	for _, hostTransport := range hostTransports {
+		if !hostTransport.insecureSkipVerify {
+			skip = false
+			pools = append(pools, hostTransport.rootCAs)
+		}
+	}
+	if skip {
+		return nil
+	}
+	if len(pools) == 0 {
+		pools = append(pools, nil)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	var err error
+	for _, pool := range pools {
+		_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+			DNSName:       cs.ServerName,
+			Roots:         pool,
+			Intermediates: intermediates,
+		})
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Select the client certificate which is issued by one of CAs which the server accepts.
+// The request of the certificate does not have the host, so the only certificate is sent if the server does not list CAs.
+func hostClientCertificate(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	certs := defaultHosts.certificates()
+	if len(cri.AcceptableCAs) != 0 {
+		for _, cert := range certs {
+			if cri.SupportsCertificate(cert) == nil {
+				return cert, nil
+			}
+		}
+	}
+
+	if len(certs) == 1 && len(cri.AcceptableCAs) == 0 {
+		return certs[0], nil
+	}
+	return &tls.Certificate{}, nil
+}
+
+// Add the CA bundle to the system CA pool.
+func loadRootCAs(caFile string) (*x509.CertPool, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil || rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+
+	caBytes, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rootCAs.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("invalid CA bundle " + caFile)
+	}
+
+	return rootCAs, nil
+}
+
+func hasAuth(transport app.Transport) bool {
+	return transport.BearerToken != "" || transport.BasicAuth.UserName != ""
+}
+
+// The host with the port, and the default port of the scheme is added if the URL omits it.
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+
+	switch u.Scheme {
+	case "https":
+		return net.JoinHostPort(u.Hostname(), "443")
+	case "http":
+		return net.JoinHostPort(u.Hostname(), "80")
+	}
+	return u.Host
+}
+
+func hostName(hostPort string) string {
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return hostPort
+	}
+	return host
+}
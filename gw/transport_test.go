@@ -0,0 +1,143 @@
+package gw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+)
+
+// Issue the certificate by the parent. The certificate is self-signed if the parent is nil.
+func newTestCert(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.DNSNames = []string{name}
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// The CA of the private chain only verifies the host of the private chain.
+func TestVerifyHostConnectionByHostCA(t *testing.T) {
+	ca, caKey := newTestCert(t, "private ca", true, nil, nil)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitDefaultTransport(map[string]app.Transport{
+		"https://private-lcd.example:1317": {CAFile: caFile},
+		"https://public-lcd.example":       {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privCert, _ := newTestCert(t, "private-lcd.example", false, ca, caKey)
+	err = verifyHostConnection(tls.ConnectionState{ServerName: "private-lcd.example", PeerCertificates: []*x509.Certificate{privCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubCert, _ := newTestCert(t, "public-lcd.example", false, ca, caKey)
+	err = verifyHostConnection(tls.ConnectionState{ServerName: "public-lcd.example", PeerCertificates: []*x509.Certificate{pubCert}})
+	if err == nil {
+		t.Fatal("the certificate of the public chain is verified by the CA of the private chain")
+	}
+}
+
+// LCDs of the same host name on different ports are found by the port, and the server is not skipped by one of them.
+func TestHostTransportsSameHostName(t *testing.T) {
+	err := InitDefaultTransport(map[string]app.Transport{
+		"https://lcd.example:1317": {InsecureSkipVerify: true, Proxy: "http://proxy-a.example:3128"},
+		"https://lcd.example":      {Proxy: "http://proxy-b.example:3128"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := newTestCert(t, "lcd.example", false, nil, nil)
+	for i := 0; i < 20; i++ {
+		for lcd, proxy := range map[string]string{
+			"https://lcd.example:1317": "proxy-a.example:3128",
+			"https://lcd.example:443":  "proxy-b.example:3128",
+			"https://lcd.example":      "proxy-b.example:3128",
+		} {
+			lcdUrl, err := url.Parse(lcd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hostTransport, ok := defaultHosts.find(hostPort(lcdUrl))
+			if !ok || hostTransport.proxy.Host != proxy {
+				t.Fatalf("the proxy of %s is not %s", lcd, proxy)
+			}
+		}
+
+		err = verifyHostConnection(tls.ConnectionState{ServerName: "lcd.example", PeerCertificates: []*x509.Certificate{cert}})
+		if err == nil {
+			t.Fatal("verification is skipped though one of LCDs of the host name verifies the server")
+		}
+	}
+}
+
+// The authorization of the public chain is refused, because the XPLA client can not add it.
+func TestInitChainTransportsRefusesPublicAuth(t *testing.T) {
+	var conf app.ConfigType
+	conf.PublicChain.LCD = "https://public-lcd.example"
+	conf.PrivateChain.LCD = "https://private-lcd.example"
+	conf.PublicChain.Transport.BearerToken = "token"
+
+	err := InitChainTransports(conf)
+	if err == nil {
+		t.Fatal("the bearer token of the public chain is not refused")
+	}
+
+	conf.PublicChain.Transport.BearerToken = ""
+	conf.PrivateChain.Transport.BearerToken = "token"
+	err = InitChainTransports(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,163 @@
+package gw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla.go/client"
+	xtypes "github.com/Moonyongjung/xpla.go/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Export the proof bundle of the block which includes the transaction of the private chain.
+// The bundle has the inclusion proof of the transaction against the data hash of the block,
+// so the transaction is linked to the anchoring transaction of the public chain.
+// If the height is empty, it is found by the transaction query to the private chain.
+func ExportTxProof(a *types.App, contractAddr, blockApi, txHash, height string) (types.ProofBundle, error) {
+	txHash = strings.ToUpper(txHash)
+
+	if height == "" {
+		var err error
+		height, err = queryPrivTxHeight(a.PrivClient, txHash)
+		if err != nil {
+			return types.ProofBundle{}, err
+		}
+	}
+
+	bundle, err := ExportProof(a, contractAddr, blockApi, height)
+	if err != nil {
+		return types.ProofBundle{}, err
+	}
+
+	txProof, err := NewTxProof(bundle.Header, txHash)
+	if err != nil {
+		return types.ProofBundle{}, err
+	}
+	bundle.TxProof = &txProof
+
+	return bundle, nil
+}
+
+// Make the inclusion proof of the transaction by rebuilding the merkle tree of transactions of the block.
+// The root is the data hash, so the block of which transactions are not matched with its header is refused.
+func NewTxProof(header types.BlockHeader, txHash string) (types.TxProof, error) {
+	if !isTendermintHeader(header) {
+		return types.TxProof{}, errors.New("the inclusion proof of the transaction only supports the tendermint block")
+	}
+
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return types.TxProof{}, errors.New("invalid tx hash " + txHash)
+	}
+
+	txs := make(tmtypes.Txs, len(header.Txs))
+	for i, tx := range header.Txs {
+		txs[i], err = base64.StdEncoding.DecodeString(tx)
+		if err != nil {
+			return types.TxProof{}, err
+		}
+	}
+
+	index := txs.IndexByHash(hash)
+	if index < 0 {
+		return types.TxProof{}, errors.New("the tx " + txHash + " is not found in the block of height " + header.Height)
+	}
+
+	proof := txs.Proof(index)
+
+	dataHash := hexUpper(proof.RootHash)
+	if dataHash != header.DataHash {
+		return types.TxProof{}, errors.New("the data hash which is rebuilt from txs is not matched with the header, rebuilt=" + dataHash + ", header=" + header.DataHash)
+	}
+
+	var aunts []string
+	for _, aunt := range proof.Proof.Aunts {
+		aunts = append(aunts, hexUpper(aunt))
+	}
+
+	return types.NewTxProof(txHash, index, len(txs), hexUpper(proof.Proof.LeafHash), aunts, dataHash), nil
+}
+
+// Check the inclusion path of the transaction leads to the data hash of the header.
+func CheckTxProof(header types.BlockHeader, txProof types.TxProof) error {
+	if txProof.DataHash != header.DataHash {
+		return errors.New("the data hash of the proof is not matched with the header, proof=" + txProof.DataHash + ", header=" + header.DataHash)
+	}
+
+	txHash, err := hex.DecodeString(txProof.TxHash)
+	if err != nil {
+		return errors.New("invalid tx hash " + txProof.TxHash)
+	}
+
+	// The merkle path does not bind the total, e.g. the first of 5 txs has the same path as the first of 6 txs.
+	// So the index and the total are checked against transactions of the header.
+	if len(header.Txs) != 0 {
+		if txProof.Total != len(header.Txs) || txProof.Index < 0 || txProof.Index >= txProof.Total {
+			return errors.New("the index or the total of the proof is not matched with txs of the header, index=" + strconv.Itoa(txProof.Index) + ", total=" + strconv.Itoa(txProof.Total) + ", txs=" + strconv.Itoa(len(header.Txs)))
+		}
+
+		tx, err := base64.StdEncoding.DecodeString(header.Txs[txProof.Index])
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(tmtypes.Tx(tx).Hash(), txHash) {
+			return errors.New("the tx of the index " + strconv.Itoa(txProof.Index) + " is not the tx " + txProof.TxHash)
+		}
+	}
+
+	root, err := hex.DecodeString(txProof.DataHash)
+	if err != nil {
+		return errors.New("invalid data hash " + txProof.DataHash)
+	}
+
+	leafHash, err := hex.DecodeString(txProof.LeafHash)
+	if err != nil {
+		return errors.New("invalid leaf hash " + txProof.LeafHash)
+	}
+
+	proof := merkle.Proof{
+		Total:    int64(txProof.Total),
+		Index:    int64(txProof.Index),
+		LeafHash: leafHash,
+	}
+	for _, aunt := range txProof.Aunts {
+		auntHash, err := hex.DecodeString(aunt)
+		if err != nil {
+			return errors.New("invalid aunt " + aunt)
+		}
+		proof.Aunts = append(proof.Aunts, auntHash)
+	}
+
+	return proof.Verify(root, txHash)
+}
+
+// Get the height of the block which includes the transaction of the private chain.
+func queryPrivTxHeight(xplac *client.XplaClient, txHash string) (string, error) {
+	res, err := xplac.Tx(xtypes.QueryTxMsg{Value: txHash}).Query()
+	if err != nil {
+		return "", err
+	}
+
+	var tx proofTxResponse
+	err = json.Unmarshal([]byte(res), &tx)
+	if err != nil {
+		return "", err
+	}
+
+	if tx.TxResponse.Height == "" {
+		return "", errors.New("the height of the tx " + txHash + " is not found")
+	}
+
+	return tx.TxResponse.Height, nil
+}
+
+func hexUpper(bytes []byte) string {
+	return strings.ToUpper(hex.EncodeToString(bytes))
+}
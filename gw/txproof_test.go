@@ -0,0 +1,158 @@
+package gw
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Moonyongjung/xpla-anchor/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// The header of the tendermint block which includes n transactions.
+func newTestTxsHeader(n int) (types.BlockHeader, tmtypes.Txs) {
+	var txs tmtypes.Txs
+	var encoded []string
+	for i := 0; i < n; i++ {
+		tx := tmtypes.Tx("tx-" + strconv.Itoa(n) + "-" + strconv.Itoa(i))
+		txs = append(txs, tx)
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(tx))
+	}
+
+	var header types.BlockHeader
+	header.VersionBlock = "11"
+	header.Height = "10"
+	header.Txs = encoded
+	header.DataHash = hexUpper(txs.Hash())
+
+	return header, txs
+}
+
+// Flip the first byte of the hex hash.
+func tamperHex(hash string) string {
+	if strings.HasPrefix(hash, "00") {
+		return "FF" + hash[2:]
+	}
+	return "00" + hash[2:]
+}
+
+// The proof of every transaction is verified against the data hash, and the tampered proof is refused.
+func TestTxProof(t *testing.T) {
+	otherTxHash := hexUpper(tmtypes.Tx("other tx").Hash())
+
+	testCases := []struct {
+		name   string
+		minTxs int
+		tamper func(txProof *types.TxProof, total int)
+	}{
+		{"aunt", 2, func(txProof *types.TxProof, total int) {
+			txProof.Aunts[len(txProof.Aunts)-1] = tamperHex(txProof.Aunts[len(txProof.Aunts)-1])
+		}},
+		{"missing aunt", 2, func(txProof *types.TxProof, total int) { txProof.Aunts = txProof.Aunts[1:] }},
+		{"extra aunt", 1, func(txProof *types.TxProof, total int) { txProof.Aunts = append(txProof.Aunts, otherTxHash) }},
+		{"leaf hash", 1, func(txProof *types.TxProof, total int) { txProof.LeafHash = tamperHex(txProof.LeafHash) }},
+		{"next index", 2, func(txProof *types.TxProof, total int) { txProof.Index = (txProof.Index + 1) % total }},
+		{"out of range index", 1, func(txProof *types.TxProof, total int) { txProof.Index = total }},
+		{"negative index", 1, func(txProof *types.TxProof, total int) { txProof.Index = -1 }},
+		{"bigger total", 1, func(txProof *types.TxProof, total int) { txProof.Total = total + 1 }},
+		{"double total", 1, func(txProof *types.TxProof, total int) { txProof.Total = total * 2 }},
+		{"smaller total", 2, func(txProof *types.TxProof, total int) { txProof.Total = total - 1 }},
+		{"tx hash", 1, func(txProof *types.TxProof, total int) { txProof.TxHash = otherTxHash }},
+		{"data hash", 1, func(txProof *types.TxProof, total int) { txProof.DataHash = tamperHex(txProof.DataHash) }},
+	}
+
+	for _, n := range []int{1, 2, 3, 5, 8} {
+		header, txs := newTestTxsHeader(n)
+
+		for index, tx := range txs {
+			txHash := hexUpper(tx.Hash())
+
+			t.Run(strconv.Itoa(n)+" txs index "+strconv.Itoa(index), func(t *testing.T) {
+				txProof, err := NewTxProof(header, txHash)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if txProof.Index != index || txProof.Total != n || txProof.DataHash != hexUpper(txs.Hash()) {
+					t.Fatalf("index = %d, total = %d, data hash = %s", txProof.Index, txProof.Total, txProof.DataHash)
+				}
+
+				err = CheckTxProof(header, txProof)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, tc := range testCases {
+					if n < tc.minTxs {
+						continue
+					}
+
+					tampered := txProof
+					tampered.Aunts = append([]string{}, txProof.Aunts...)
+					tc.tamper(&tampered, n)
+
+					if CheckTxProof(header, tampered) == nil {
+						t.Fatalf("the proof of which the %s is tampered is not refused", tc.name)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Without transactions of the header, the merkle path still refuses the tampered aunt and another tx.
+func TestTxProofWithoutTxs(t *testing.T) {
+	header, txs := newTestTxsHeader(5)
+
+	for index, tx := range txs {
+		txProof, err := NewTxProof(header, hexUpper(tx.Hash()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header := header
+		header.Txs = nil
+
+		err = CheckTxProof(header, txProof)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tampered := txProof
+		tampered.Aunts = append([]string{}, txProof.Aunts...)
+		tampered.Aunts[0] = tamperHex(tampered.Aunts[0])
+		if CheckTxProof(header, tampered) == nil {
+			t.Fatalf("the proof of the index %d of which the aunt is tampered is not refused", index)
+		}
+
+		tampered = txProof
+		tampered.TxHash = hexUpper(txs[(index+1)%len(txs)].Hash())
+		if CheckTxProof(header, tampered) == nil {
+			t.Fatalf("the proof of the index %d with another tx is not refused", index)
+		}
+	}
+}
+
+// The tx which is not included in the block, and the block of which txs are not matched with the data hash are refused.
+func TestNewTxProofRefused(t *testing.T) {
+	header, txs := newTestTxsHeader(3)
+
+	_, err := NewTxProof(header, hexUpper(tmtypes.Tx("other tx").Hash()))
+	if err == nil || !strings.Contains(err.Error(), "is not found") {
+		t.Fatalf("err = %v, want not found", err)
+	}
+
+	tampered := header
+	tampered.DataHash = tamperHex(header.DataHash)
+	_, err = NewTxProof(tampered, hexUpper(txs[0].Hash()))
+	if err == nil || !strings.Contains(err.Error(), "not matched") {
+		t.Fatalf("err = %v, want not matched", err)
+	}
+
+	legacy := header
+	legacy.VersionBlock = ""
+	_, err = NewTxProof(legacy, hexUpper(txs[0].Hash()))
+	if err == nil {
+		t.Fatal("the proof of the block which is not tendermint is made")
+	}
+}
@@ -0,0 +1,232 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/app"
+	"github.com/Moonyongjung/xpla-anchor/types"
+	"github.com/Moonyongjung/xpla-anchor/util"
+	"github.com/Moonyongjung/xpla.go/client"
+)
+
+const (
+	DefaultVerifyConcurrency = 4
+	verifyProgressCount      = 1000
+)
+
+// The block of the height is not recorded in the contract or the memo index.
+var ErrNotRecorded = errors.New("block is not recorded")
+
+// Normalize the recorded block info, because hashes of the contract can be recorded by another encoding.
+func NormalizeData(recorded types.Data) types.Data {
+	normalized := recorded
+	normalized.BlockHash = NormalizeHash(recorded.BlockHash)
+	normalized.DataMerkle = NormalizeHash(recorded.DataMerkle)
+
+	timestamp, err := NormalizeTime(recorded.Timestamp)
+	if err == nil {
+		normalized.Timestamp = timestamp
+	}
+
+	return normalized
+}
+
+// Get fields which are not matched between the block of the private chain and the normalized recorded block info.
+// If the hash of the private chain does not belong to its header, fields of the header which cause it are reported by HeaderMismatches.
+// The header is not reported if the recomputed hash is the recorded hash, because only the reported hash is wrong.
+// The empty chain ID or the empty last hash is not checked.
+func CompareBlock(header types.BlockHeader, chainID, lastHash string, recorded types.Data) []string {
+	var mismatches []string
+
+	if header.Height != recorded.Height {
+		mismatches = append(mismatches, types.VerifyFieldHeight)
+	}
+	if header.Hash != recorded.BlockHash {
+		mismatches = append(mismatches, types.VerifyFieldHash)
+	}
+	if CheckHeaderHash(header) != nil {
+		if computed, err := ComputeHeaderHash(header); err != nil || computed != recorded.BlockHash {
+			mismatches = append(mismatches, HeaderMismatches(header, chainID, lastHash)...)
+		}
+	}
+	if header.DataHash != recorded.DataMerkle {
+		mismatches = append(mismatches, types.VerifyFieldMerkle)
+	}
+	if header.Time != recorded.Timestamp {
+		mismatches = append(mismatches, types.VerifyFieldTimestamp)
+	}
+
+	return mismatches
+}
+
+// Verify the recorded block info of the height.
+func VerifyHeight(a *types.App, xplac *client.XplaClient, contractAddr, blockApi, height string) types.VerifyResult {
+	result := types.NewVerifyResult(height)
+
+	recorded, err := QueryRecordedBlockData(xplac, contractAddr, height)
+	if err != nil {
+		if errors.Is(err, ErrNotRecorded) {
+			result.Missing = true
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	res, err := DoRequest(a, blockApi, height)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	header, err := ParseBlock(res)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var lastHash string
+	if CheckHeaderHash(header) != nil {
+		lastHash = LastBlockHash(a, blockApi, header)
+	}
+
+	chainID := app.AppFile().Get().Config.PrivateChain.ChainID
+	result.Mismatches = CompareBlock(header, chainID, lastHash, NormalizeData(recorded))
+
+	return result
+}
+
+// Get the hash of the previous block in order to check the last block ID of the header.
+// The previous block is used only if its hash belongs to its header, and it is empty for the first block or if the request fails.
+func LastBlockHash(a *types.App, blockApi string, header types.BlockHeader) string {
+	height := util.FromStringToUint64(header.Height)
+	if height <= 1 {
+		return ""
+	}
+
+	res, err := DoRequest(a, blockApi, util.FromUint64ToString(height-1))
+	if err != nil {
+		return ""
+	}
+
+	last, err := ParseBlock(res)
+	if err != nil || !isTendermintHeader(last) || CheckHeaderHash(last) != nil {
+		return ""
+	}
+
+	return last.Hash
+}
+
+// Verify heights by workers.
+// Each worker owns the copy of the XPLA client, and heights are started by the rate (per second) if it is set.
+// Results are sorted by the height. If the context is done, heights which are not started yet are not verified.
+func VerifyHeights(ctx context.Context, a *types.App, contractAddr, blockApi string, heights []uint64, concurrency int, rate float64) []types.VerifyResult {
+	if concurrency <= 0 {
+		concurrency = DefaultVerifyConcurrency
+	}
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	jobs := make(chan uint64)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []types.VerifyResult
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			xplac := cloneClient(a.PubClient)
+			for height := range jobs {
+				result := VerifyHeight(a, xplac, contractAddr, blockApi, util.FromUint64ToString(height))
+				if !result.Consistent() {
+					util.LogWarning(util.R("NOT CONSISTENT"), util.BB("height=")+result.Height, verifyResultReason(result))
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				if len(results)%verifyProgressCount == 0 {
+					util.LogInfo(util.BB("verified=") + util.ToString(len(results), "0") + "/" + util.ToString(len(heights), "0"))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+queue:
+	for _, height := range heights {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break queue
+			}
+		}
+
+		select {
+		case jobs <- height:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return util.FromStringToUint64(results[i].Height) < util.FromStringToUint64(results[j].Height)
+	})
+
+	return results
+}
+
+// Get heights of the range.
+// If the sample is set and it is less than the range, heights are sampled randomly.
+func VerifyTargetHeights(fromHeight, toHeight uint64, sample int) []uint64 {
+	count := toHeight - fromHeight + 1
+
+	if sample <= 0 || uint64(sample) >= count {
+		heights := make([]uint64, 0, count)
+		for height := fromHeight; height <= toHeight; height++ {
+			heights = append(heights, height)
+		}
+		return heights
+	}
+
+	picked := make(map[uint64]bool)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for len(picked) < sample {
+		picked[fromHeight+uint64(r.Int63n(int64(count)))] = true
+	}
+
+	heights := make([]uint64, 0, sample)
+	for height := range picked {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	return heights
+}
+
+func verifyResultReason(result types.VerifyResult) string {
+	switch {
+	case result.Error != "":
+		return util.BB("error=") + result.Error
+	case result.Missing:
+		return util.BB("missing record")
+	default:
+		return util.BB("mismatched fields=") + strings.Join(result.Mismatches, ",")
+	}
+}
@@ -0,0 +1,360 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Moonyongjung/xpla-anchor/util/fsutil"
+)
+
+const persistPeriod = 1000
+
+var stateInstance *State
+var stateOnce sync.Once
+
+// The state of the gateway.
+// Heights, sequences of accounts and indexes of DB logs are changed by several goroutines,
+// so every access is protected by the mutex.
+// If the persistence is enabled, the state is written to the file periodically, and it is inspectable from the CLI.
+type State struct {
+	mu    sync.Mutex
+	data  Snapshot
+	path  string
+	dirty bool
+	// The loop which writes the state is running.
+	persisting bool
+}
+
+// The copy of the state.
+type Snapshot struct {
+	NextHeight      uint64            `json:"next_height"`
+	RecordedLatest  uint64            `json:"recorded_latest"`
+	SubmittedLatest uint64            `json:"submitted_latest"`
+	Sequences       map[string]uint64 `json:"sequences"`
+	Paused          bool              `json:"paused"`
+	ManualPause     bool              `json:"manual_pause"`
+	LogDB           bool              `json:"log_db"`
+	InfoLogIndex    uint64            `json:"info_log_index"`
+	ErrLogIndex     uint64            `json:"err_log_index"`
+	// Anchoring transactions which are broadcasted, but not confirmed yet, by the tx hash.
+	// The transaction is kept as JSON, because the state does not depend on types of the gateway.
+	PendingTxs map[string]json.RawMessage `json:"pending_txs"`
+	UpdatedAt  string                     `json:"updated_at"`
+}
+
+func Mng() *State {
+	stateOnce.Do(func() {
+		stateInstance = &State{
+			data: Snapshot{
+				Sequences:  make(map[string]uint64),
+				PendingTxs: make(map[string]json.RawMessage),
+			},
+		}
+	})
+	return stateInstance
+}
+
+// Read the state which is persisted by the previous run.
+// Heights and sequences are synchronized with chains when the gateway starts, because chains are the source of truth,
+// so only the manual pause and pending transactions are restored. The gateway which is paused by the operator is still paused
+// after the restart, and transactions which are broadcasted by the previous run are confirmed by the next run.
+// It returns false if the file does not exist.
+func (s *State) Restore(path string) (Snapshot, bool, error) {
+	snapshot, err := Load(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshot, false, nil
+		}
+		return snapshot, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.ManualPause = snapshot.ManualPause
+	for hash, tx := range snapshot.PendingTxs {
+		s.data.PendingTxs[hash] = tx
+	}
+	s.touch()
+
+	return snapshot, true, nil
+}
+
+// Write the state to the file periodically.
+func (s *State) Persist(path string) error {
+	s.mu.Lock()
+	s.path = path
+	s.dirty = true
+	persisting := s.persisting
+	s.persisting = true
+	s.mu.Unlock()
+
+	err := s.flush()
+	if err != nil {
+		return err
+	}
+
+	// The file is written by one loop even if the persistence is enabled again.
+	if persisting {
+		return nil
+	}
+
+	go func() {
+		for {
+			time.Sleep(time.Millisecond * time.Duration(persistPeriod))
+			s.flush()
+		}
+	}()
+
+	return nil
+}
+
+// Write the state to the file at once, e.g. before the gateway exits.
+func (s *State) Flush() error {
+	return s.flush()
+}
+
+func (s *State) flush() error {
+	s.mu.Lock()
+	if s.path == "" || !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	path := s.path
+	snapshot := s.snapshot()
+	s.dirty = false
+	s.mu.Unlock()
+
+	return save(path, snapshot)
+}
+
+// The next block height to request to the private chain.
+func (s *State) SetNextHeight(height uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextHeight = height
+	s.touch()
+}
+
+func (s *State) NextHeight() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.NextHeight
+}
+
+func (s *State) IncreaseNextHeight() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.NextHeight++
+	s.touch()
+}
+
+// The latest block height in the contract when the gateway starts.
+func (s *State) SetRecordedLatest(height uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.RecordedLatest = height
+	s.touch()
+}
+
+func (s *State) RecordedLatest() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.RecordedLatest
+}
+
+// Raise the highest latest height of broadcasted anchoring transactions.
+func (s *State) RaiseSubmittedLatest(height uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if height <= s.data.SubmittedLatest {
+		return
+	}
+	s.data.SubmittedLatest = height
+	s.touch()
+}
+
+func (s *State) SubmittedLatest() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.SubmittedLatest
+}
+
+// The sequence of each account which sends anchoring transactions.
+func (s *State) SetSequence(addr string, sequence uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Sequences[addr] = sequence
+	s.touch()
+}
+
+func (s *State) Sequence(addr string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.Sequences[addr]
+}
+
+func (s *State) IncreaseSequence(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Sequences[addr]++
+	s.touch()
+}
+
+// The gateway is paused, and it is paused manually by the operator.
+func (s *State) SetPaused(paused, manual bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Paused = paused
+	s.data.ManualPause = manual
+	s.touch()
+}
+
+func (s *State) ManualPause() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.ManualPause
+}
+
+// The anchoring transaction which is broadcasted is kept until it is confirmed.
+func (s *State) AddPendingTx(txHash string, tx json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.PendingTxs[txHash] = tx
+	s.touch()
+}
+
+func (s *State) RemovePendingTx(txHash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.PendingTxs[txHash]; !ok {
+		return
+	}
+	delete(s.data.PendingTxs, txHash)
+	s.touch()
+}
+
+func (s *State) PendingTxs() map[string]json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pendingTxs := make(map[string]json.RawMessage)
+	for hash, tx := range s.data.PendingTxs {
+		pendingTxs[hash] = tx
+	}
+
+	return pendingTxs
+}
+
+// Logs are saved to DB.
+func (s *State) UseDb() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.LogDB = true
+}
+
+func (s *State) IsUseDb() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.LogDB
+}
+
+// Set the next indexes of DB logs.
+func (s *State) SetLogIndex(infoIndex, errIndex uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.InfoLogIndex = infoIndex
+	s.data.ErrLogIndex = errIndex
+}
+
+// Get the index of the new log and increase it.
+func (s *State) TakeInfoLogIndex() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.data.InfoLogIndex
+	s.data.InfoLogIndex++
+
+	return index
+}
+
+func (s *State) TakeErrLogIndex() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.data.ErrLogIndex
+	s.data.ErrLogIndex++
+
+	return index
+}
+
+func (s *State) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snapshot()
+}
+
+func (s *State) snapshot() Snapshot {
+	snapshot := s.data
+	snapshot.Sequences = make(map[string]uint64)
+	for addr, sequence := range s.data.Sequences {
+		snapshot.Sequences[addr] = sequence
+	}
+	snapshot.PendingTxs = make(map[string]json.RawMessage)
+	for hash, tx := range s.data.PendingTxs {
+		snapshot.PendingTxs[hash] = tx
+	}
+
+	return snapshot
+}
+
+func (s *State) touch() {
+	s.dirty = true
+	s.data.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// Load the state which is persisted by the gateway.
+func Load(path string) (Snapshot, error) {
+	var snapshot Snapshot
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = json.Unmarshal(bytes, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	return snapshot, nil
+}
+
+func save(path string, snapshot Snapshot) error {
+	bytes, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, bytes)
+}
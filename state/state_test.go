@@ -0,0 +1,132 @@
+package state
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func newTestState() *State {
+	return &State{
+		data: Snapshot{
+			Sequences:  make(map[string]uint64),
+			PendingTxs: make(map[string]json.RawMessage),
+		},
+	}
+}
+
+// The state is changed, read and persisted by many goroutines at once.
+// Run with -race.
+func TestStateConcurrentAccess(t *testing.T) {
+	const (
+		workers    = 16
+		iterations = 500
+		accounts   = 4
+	)
+
+	s := newTestState()
+	path := filepath.Join(t.TempDir(), "state", "gateway.json")
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			addr := "account" + strconv.Itoa(w%accounts)
+			for i := 0; i < iterations; i++ {
+				s.IncreaseNextHeight()
+				s.IncreaseSequence(addr)
+				_ = s.NextHeight()
+				_ = s.Sequence(addr)
+				_ = s.TakeInfoLogIndex()
+
+				snapshot := s.Snapshot()
+				// The snapshot is the copy, so changing it does not change the state.
+				snapshot.Sequences[addr] = 0
+
+				if i%100 == 0 {
+					err := s.Persist(path)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if got, want := s.NextHeight(), uint64(workers*iterations); got != want {
+		t.Fatalf("next height = %d, want %d", got, want)
+	}
+
+	for a := 0; a < accounts; a++ {
+		addr := "account" + strconv.Itoa(a)
+		if got, want := s.Sequence(addr), uint64(workers/accounts*iterations); got != want {
+			t.Fatalf("sequence of %s = %d, want %d", addr, got, want)
+		}
+	}
+
+	if got, want := s.TakeInfoLogIndex(), uint64(workers*iterations); got != want {
+		t.Fatalf("info log index = %d, want %d", got, want)
+	}
+
+	err := s.flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.NextHeight != uint64(workers*iterations) {
+		t.Fatalf("persisted next height = %d, want %d", snapshot.NextHeight, workers*iterations)
+	}
+}
+
+// Only the manual pause and pending transactions are restored from the persisted state.
+func TestStateRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.json")
+
+	s := newTestState()
+	_, ok, err := s.Restore(path)
+	if err != nil || ok {
+		t.Fatalf("restore of the missing file: ok=%v, err=%v", ok, err)
+	}
+
+	s.SetNextHeight(100)
+	s.SetSequence("account", 7)
+	s.SetPaused(true, true)
+	s.AddPendingTx("CONFIRMED", json.RawMessage(`{"TxHash":"CONFIRMED"}`))
+	s.AddPendingTx("PENDING", json.RawMessage(`{"TxHash":"PENDING"}`))
+	s.RemovePendingTx("CONFIRMED")
+	err = save(path, s.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestState()
+	snapshot, ok, err := restored.Restore(path)
+	if err != nil || !ok {
+		t.Fatalf("restore: ok=%v, err=%v", ok, err)
+	}
+
+	if snapshot.NextHeight != 100 || snapshot.Sequences["account"] != 7 {
+		t.Fatalf("loaded snapshot = %+v", snapshot)
+	}
+	if !restored.ManualPause() {
+		t.Fatal("manual pause is not restored")
+	}
+	if restored.NextHeight() != 0 || restored.Sequence("account") != 0 {
+		t.Fatal("heights and sequences must be synchronized with chains, not restored")
+	}
+
+	pendingTxs := restored.PendingTxs()
+	if _, ok := pendingTxs["PENDING"]; !ok || len(pendingTxs) != 1 {
+		t.Fatalf("restored pending txs = %v", pendingTxs)
+	}
+}
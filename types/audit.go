@@ -0,0 +1,18 @@
+package types
+
+// The progress of the audit which is saved in order to resume after the restart.
+// The audited height is the highest height which is checked as the recent batch.
+// Rechecks are heights which are missing or failed to be checked, and the value is the unix time (milliseconds) of the first check.
+type AuditProgress struct {
+	AuditedHeight string           `json:"audited_height"`
+	Rechecks      map[string]int64 `json:"rechecks"`
+}
+
+func NewAuditProgress(auditedHeight string, rechecks map[string]int64) AuditProgress {
+	var auditProgress AuditProgress
+
+	auditProgress.AuditedHeight = auditedHeight
+	auditProgress.Rechecks = rechecks
+
+	return auditProgress
+}
@@ -0,0 +1,19 @@
+package types
+
+// The progress of the backfill which is saved in order to resume the interrupted run.
+// The confirmed height is the highest height that every block from the start height is anchored.
+type BackfillProgress struct {
+	FromHeight      string `json:"from_height"`
+	ToHeight        string `json:"to_height"`
+	ConfirmedHeight string `json:"confirmed_height"`
+}
+
+func NewBackfillProgress(fromHeight, toHeight, confirmedHeight string) BackfillProgress {
+	var backfillProgress BackfillProgress
+
+	backfillProgress.FromHeight = fromHeight
+	backfillProgress.ToHeight = toHeight
+	backfillProgress.ConfirmedHeight = confirmedHeight
+
+	return backfillProgress
+}
@@ -17,6 +17,7 @@ type Block struct {
 		Header struct {
 			Version struct {
 				Block string `json:"block"`
+				App   string `json:"app"`
 			} `json:"version"`
 			ChainID     string `json:"chain_id"`
 			Height      string `json:"height"`
@@ -39,7 +40,7 @@ type Block struct {
 			ProposerAddress    string `json:"proposer_address"`
 		} `json:"header"`
 		Data struct {
-			Txs []interface{} `json:"txs"`
+			Txs []string `json:"txs"`
 		} `json:"data"`
 		Evidence struct {
 			Evidence []interface{} `json:"evidence"`
@@ -63,3 +64,68 @@ type Block struct {
 		} `json:"last_commit"`
 	} `json:"block"`
 }
+
+// The structure of the block from the tendermint service of the cosmos SDK.
+// Hashes and addresses are encoded by base64, and the proposer address of the sdk block is encoded by bech32.
+type TendermintBlock struct {
+	BlockID  TendermintBlockID     `json:"block_id"`
+	Block    TendermintBlockDetail `json:"block"`
+	SdkBlock TendermintBlockDetail `json:"sdk_block"`
+}
+
+type TendermintBlockID struct {
+	Hash          string `json:"hash"`
+	PartSetHeader struct {
+		Total int    `json:"total"`
+		Hash  string `json:"hash"`
+	} `json:"part_set_header"`
+}
+
+type TendermintBlockDetail struct {
+	Header struct {
+		Version struct {
+			Block string `json:"block"`
+			App   string `json:"app"`
+		} `json:"version"`
+		ChainID            string            `json:"chain_id"`
+		Height             string            `json:"height"`
+		Time               string            `json:"time"`
+		LastBlockID        TendermintBlockID `json:"last_block_id"`
+		LastCommitHash     string            `json:"last_commit_hash"`
+		DataHash           string            `json:"data_hash"`
+		ValidatorsHash     string            `json:"validators_hash"`
+		NextValidatorsHash string            `json:"next_validators_hash"`
+		ConsensusHash      string            `json:"consensus_hash"`
+		AppHash            string            `json:"app_hash"`
+		LastResultsHash    string            `json:"last_results_hash"`
+		EvidenceHash       string            `json:"evidence_hash"`
+		ProposerAddress    string            `json:"proposer_address"`
+	} `json:"header"`
+	Data struct {
+		Txs []string `json:"txs"`
+	} `json:"data"`
+}
+
+// The block header which is normalized by the block adapter.
+// Hashes and addresses are encoded by the upper case hex, and the time is RFC3339 in UTC.
+type BlockHeader struct {
+	Hash                string   `json:"hash"`
+	VersionBlock        string   `json:"version_block"`
+	VersionApp          string   `json:"version_app"`
+	ChainID             string   `json:"chain_id"`
+	Height              string   `json:"height"`
+	Time                string   `json:"time"`
+	LastBlockHash       string   `json:"last_block_hash"`
+	LastBlockPartsTotal int      `json:"last_block_parts_total"`
+	LastBlockPartsHash  string   `json:"last_block_parts_hash"`
+	LastCommitHash      string   `json:"last_commit_hash"`
+	DataHash            string   `json:"data_hash"`
+	ValidatorsHash      string   `json:"validators_hash"`
+	NextValidatorsHash  string   `json:"next_validators_hash"`
+	ConsensusHash       string   `json:"consensus_hash"`
+	AppHash             string   `json:"app_hash"`
+	LastResultsHash     string   `json:"last_results_hash"`
+	EvidenceHash        string   `json:"evidence_hash"`
+	ProposerAddress     string   `json:"proposer_address"`
+	Txs                 []string `json:"txs"`
+}
@@ -1,6 +1,54 @@
 package types
 
+const (
+	// Default number of aggregated batches waiting to be signed.
+	DefaultBatchQueueSize = 3
+	// Default number of anchoring transactions broadcasted but not confirmed yet.
+	DefaultMaxInFlightTx = 3
+)
+
 type Channels struct {
-	HttpClientStartSignal chan bool
-	AnchringTx            chan Anchoring
+	AnchringTx  chan Anchoring
+	RetryTx     chan Anchoring
+	PendingTx   chan PendingTx
+	ConfirmedTx chan Anchoring
+}
+
+// Make channels of the gateway pipeline.
+// The capacities bound the number of queued batches and in-flight transactions,
+// so the block fetcher only waits when both queues are full.
+func NewChannels(batchQueueSize, maxInFlightTx int) Channels {
+	if batchQueueSize <= 0 {
+		batchQueueSize = DefaultBatchQueueSize
+	}
+
+	if maxInFlightTx <= 0 {
+		maxInFlightTx = DefaultMaxInFlightTx
+	}
+
+	var channels Channels
+	channels.AnchringTx = make(chan Anchoring, batchQueueSize)
+	channels.RetryTx = make(chan Anchoring, maxInFlightTx+1)
+	channels.PendingTx = make(chan PendingTx, maxInFlightTx)
+
+	return channels
+}
+
+// The anchoring transaction which is broadcasted, but not included in a block yet.
+type PendingTx struct {
+	Anchoring Anchoring
+	Sender    string
+	Sequence  string
+	TxHash    string
+}
+
+func NewPendingTx(anchoring Anchoring, sender, sequence, txHash string) PendingTx {
+	var pendingTx PendingTx
+
+	pendingTx.Anchoring = anchoring
+	pendingTx.Sender = sender
+	pendingTx.Sequence = sequence
+	pendingTx.TxHash = txHash
+
+	return pendingTx
 }
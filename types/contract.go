@@ -3,6 +3,8 @@ package types
 const (
 	// Latest block message.
 	QueryLatestBlockMsg = `{"latest_block":{}}`
+	// Config message includes the owner and submitters.
+	QueryConfigMsg = `{"config":{}}`
 )
 
 // The type of the sending transaction for anchring.
@@ -38,12 +40,34 @@ func NewData(height, blockHash, dataMerkle, timestamp string) Data {
 	return data
 }
 
+// The message to add or remove the accounts which are able to send anchoring transactions.
+type UpdateSubmitters struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+func NewUpdateSubmitters(add, remove []string) UpdateSubmitters {
+	var updateSubmitters UpdateSubmitters
+
+	updateSubmitters.Add = append([]string{}, add...)
+	updateSubmitters.Remove = append([]string{}, remove...)
+
+	return updateSubmitters
+}
+
 type QueryLatestBlockResponse struct {
 	Data struct {
 		LatestHeight string `json:"latest_height"`
 	} `json:"data"`
 }
 
+type QueryConfigResponse struct {
+	Data struct {
+		Owner      string   `json:"owner"`
+		Submitters []string `json:"submitters"`
+	} `json:"data"`
+}
+
 type QueryBlockInfoResponse struct {
 	Data struct {
 		Height     string `json:"height"`
@@ -52,3 +76,34 @@ type QueryBlockInfoResponse struct {
 		Timestamp  string `json:"timestamp"`
 	} `json:"data"`
 }
+
+type Coin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+// The response of the transaction query by hash.
+type QueryTxResponse struct {
+	Tx struct {
+		Body struct {
+			Memo string `json:"memo"`
+		} `json:"body"`
+		AuthInfo struct {
+			Fee struct {
+				Amount []Coin `json:"amount"`
+			} `json:"fee"`
+		} `json:"auth_info"`
+	} `json:"tx"`
+	TxResponse struct {
+		Height    string `json:"height"`
+		TxHash    string `json:"txhash"`
+		Code      int    `json:"code"`
+		RawLog    string `json:"raw_log"`
+		GasWanted string `json:"gas_wanted"`
+		GasUsed   string `json:"gas_used"`
+	} `json:"tx_response"`
+}
+
+type QueryBalancesResponse struct {
+	Balances []Coin `json:"balances"`
+}
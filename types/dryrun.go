@@ -0,0 +1,31 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// The result of the anchoring transaction which is signed, but not broadcasted.
+type DryRunResult struct {
+	FirstHeight  string          `json:"first_height"`
+	LatestHeight string          `json:"latest_height"`
+	BlockCount   int             `json:"block_count"`
+	ExecMsg      json.RawMessage `json:"exec_msg,omitempty"`
+	Memo         string          `json:"memo,omitempty"`
+	Gas          uint64          `json:"gas"`
+	Fee          string          `json:"fee"`
+	TxBytes      int             `json:"tx_bytes"`
+}
+
+func NewDryRunResult(anchoring Anchoring, execMsg string, gas uint64, fee string, txBytes int) DryRunResult {
+	var dryRunResult DryRunResult
+
+	dryRunResult.FirstHeight = anchoring.Data[0].Height
+	dryRunResult.LatestHeight = anchoring.Latest
+	dryRunResult.BlockCount = len(anchoring.Data)
+	dryRunResult.ExecMsg = json.RawMessage(execMsg)
+	dryRunResult.Gas = gas
+	dryRunResult.Fee = fee
+	dryRunResult.TxBytes = txBytes
+
+	return dryRunResult
+}
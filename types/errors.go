@@ -18,6 +18,11 @@ var (
 	ErrBlockMng      = new(109, "error block management")
 	ErrQuery         = new(110, "error query")
 	ErrAccount       = new(111, "error account")
+	ErrAuthz         = new(112, "error authz")
+	ErrHA            = new(113, "error high availability")
+	ErrSupervisor    = new(114, "error supervisor")
+	ErrAudit         = new(115, "error audit")
+	ErrProof         = new(116, "error proof")
 )
 
 func new(errCode uint64, desc string) XGoError {
@@ -0,0 +1,43 @@
+package types
+
+// Status of the entry of the memo index.
+// The entry of the old index which has no status is confirmed.
+const (
+	MemoIndexPending   = "pending"
+	MemoIndexConfirmed = "confirmed"
+	MemoIndexFailed    = "failed"
+)
+
+// The batch which is anchored by the memo of the transaction.
+// The index keeps blocks of the batch, because the memo only has the commitment of them.
+// The pending entry is written before the transaction is broadcasted, and the entry of the same transaction
+// is written again when it is confirmed or failed.
+type MemoIndexEntry struct {
+	Status     string `json:"status,omitempty"`
+	ChainID    string `json:"chain_id"`
+	FromHeight string `json:"from_height"`
+	ToHeight   string `json:"to_height"`
+	Root       string `json:"root"`
+	Commitment string `json:"commitment"`
+	TxHash     string `json:"tx_hash"`
+	Data       []Data `json:"data"`
+}
+
+func NewMemoIndexEntry(status, chainID, root, commitment, txHash string, data []Data) MemoIndexEntry {
+	var memoIndexEntry MemoIndexEntry
+
+	memoIndexEntry.Status = status
+	memoIndexEntry.ChainID = chainID
+	memoIndexEntry.FromHeight = data[0].Height
+	memoIndexEntry.ToHeight = data[len(data)-1].Height
+	memoIndexEntry.Root = root
+	memoIndexEntry.Commitment = commitment
+	memoIndexEntry.TxHash = txHash
+	memoIndexEntry.Data = data
+
+	return memoIndexEntry
+}
+
+func (e MemoIndexEntry) IsConfirmed() bool {
+	return e.Status == "" || e.Status == MemoIndexConfirmed
+}
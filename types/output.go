@@ -0,0 +1,49 @@
+package types
+
+import "encoding/json"
+
+// Formats of results of query commands.
+const (
+	OutputText = "text"
+	OutputJson = "json"
+	OutputYaml = "yaml"
+)
+
+// Exit codes of the anchor.
+const (
+	ExitCodeError         = 1
+	ExitCodeNotConsistent = 2
+)
+
+type LatestBlockOutput struct {
+	LatestHeight string `json:"latest_height"`
+}
+
+type AccountBalancesOutput struct {
+	Address  string `json:"address"`
+	Balances []Coin `json:"balances"`
+}
+
+// The account is printed as the response of the public chain, because its type depends on the chain.
+type AccountInfoOutput struct {
+	Address string          `json:"address"`
+	Account json.RawMessage `json:"account"`
+}
+
+type GapsOutput struct {
+	FromHeight    string   `json:"from_height"`
+	ToHeight      string   `json:"to_height"`
+	Count         int      `json:"count"`
+	MissingRanges []string `json:"missing_ranges"`
+}
+
+func NewGapsOutput(fromHeight, toHeight string, count int, missingRanges []string) GapsOutput {
+	var gapsOutput GapsOutput
+
+	gapsOutput.FromHeight = fromHeight
+	gapsOutput.ToHeight = toHeight
+	gapsOutput.Count = count
+	gapsOutput.MissingRanges = append([]string{}, missingRanges...)
+
+	return gapsOutput
+}
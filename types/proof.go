@@ -0,0 +1,107 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// The portable evidence that the block of the private chain is anchored to the public chain.
+// The batch is blocks of the anchoring transaction, and the tx is the response of the transaction query,
+// so the bundle can be checked without the anchor.
+// The tx bytes are the raw transaction (base64) in the public block, and the hash of them is the tx hash.
+type ProofBundle struct {
+	Version         int             `json:"version"`
+	Sink            string          `json:"sink"`
+	PrivateChainID  string          `json:"private_chain_id"`
+	PublicChainID   string          `json:"public_chain_id"`
+	ContractAddress string          `json:"contract_address,omitempty"`
+	Header          BlockHeader     `json:"header"`
+	Record          Data            `json:"record"`
+	Batch           []Data          `json:"batch"`
+	TxHash          string          `json:"tx_hash"`
+	PublicHeight    string          `json:"public_height"`
+	Tx              json.RawMessage `json:"tx"`
+	TxBytes         string          `json:"tx_bytes"`
+	TxProof         *TxProof        `json:"tx_proof,omitempty"`
+	ExportedAt      string          `json:"exported_at"`
+}
+
+// The inclusion proof of the transaction of the private chain.
+// The leaf is the hash of the transaction, and the root is the data hash of the block.
+type TxProof struct {
+	TxHash   string   `json:"tx_hash"`
+	Index    int      `json:"index"`
+	Total    int      `json:"total"`
+	LeafHash string   `json:"leaf_hash"`
+	Aunts    []string `json:"aunts"`
+	DataHash string   `json:"data_hash"`
+}
+
+func NewTxProof(txHash string, index, total int, leafHash string, aunts []string, dataHash string) TxProof {
+	var txProof TxProof
+
+	txProof.TxHash = txHash
+	txProof.Index = index
+	txProof.Total = total
+	txProof.LeafHash = leafHash
+	txProof.Aunts = append([]string{}, aunts...)
+	txProof.DataHash = dataHash
+
+	return txProof
+}
+
+// The check of the proof bundle.
+type ProofCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func NewProofCheck(name string, err error) ProofCheck {
+	var proofCheck ProofCheck
+
+	proofCheck.Name = name
+	proofCheck.Passed = err == nil
+	if err != nil {
+		proofCheck.Detail = err.Error()
+	}
+
+	return proofCheck
+}
+
+// The result of the verification of the proof bundle.
+// The public LCD is set if the bundle is checked again against the public chain,
+// and the private tx hash is set if the bundle proves the transaction of the private chain.
+// The offline result is not authenticated, because the bundle itself can not prove that the transaction is in the public chain.
+type ProofVerifyResult struct {
+	PrivateTxHash string       `json:"private_tx_hash,omitempty"`
+	Height        string       `json:"height"`
+	TxHash        string       `json:"tx_hash"`
+	PublicHeight  string       `json:"public_height"`
+	PublicLCD     string       `json:"public_lcd,omitempty"`
+	Valid         bool         `json:"valid"`
+	Authenticated bool         `json:"authenticated"`
+	Checks        []ProofCheck `json:"checks"`
+}
+
+func NewProofVerifyResult(bundle ProofBundle, publicLcd string) ProofVerifyResult {
+	var proofVerifyResult ProofVerifyResult
+
+	if bundle.TxProof != nil {
+		proofVerifyResult.PrivateTxHash = bundle.TxProof.TxHash
+	}
+	proofVerifyResult.Height = bundle.Record.Height
+	proofVerifyResult.TxHash = bundle.TxHash
+	proofVerifyResult.PublicHeight = bundle.PublicHeight
+	proofVerifyResult.PublicLCD = publicLcd
+	proofVerifyResult.Valid = true
+	proofVerifyResult.Checks = []ProofCheck{}
+
+	return proofVerifyResult
+}
+
+func (p *ProofVerifyResult) Add(check ProofCheck) {
+	p.Checks = append(p.Checks, check)
+	if !check.Passed {
+		p.Valid = false
+	}
+}
@@ -0,0 +1,29 @@
+package types
+
+const (
+	RateLimitStateOk      = "ok"
+	RateLimitStateBackoff = "backoff"
+)
+
+// The state of the rate limiter for requests to the private chain.
+type RateLimitStatus struct {
+	State     string  `json:"state"`
+	Rate      float64 `json:"rate"`
+	Burst     float64 `json:"burst"`
+	Tokens    float64 `json:"tokens"`
+	BackoffMs int64   `json:"backoff_ms"`
+	Throttled uint64  `json:"throttled"`
+}
+
+func NewRateLimitStatus(state string, rate, burst, tokens float64, backoffMs int64, throttled uint64) RateLimitStatus {
+	var rateLimitStatus RateLimitStatus
+
+	rateLimitStatus.State = state
+	rateLimitStatus.Rate = rate
+	rateLimitStatus.Burst = burst
+	rateLimitStatus.Tokens = tokens
+	rateLimitStatus.BackoffMs = backoffMs
+	rateLimitStatus.Throttled = throttled
+
+	return rateLimitStatus
+}
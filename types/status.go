@@ -0,0 +1,40 @@
+package types
+
+import (
+	"github.com/Moonyongjung/xpla-anchor/state"
+)
+
+const (
+	GatewayStatusRunning   = "running"
+	GatewayStatusPaused    = "paused"
+	PauseReasonManual      = "manual"
+	PauseReasonMaintenance = "maintenance"
+)
+
+// The status of the gateway which is responded by the admin endpoint.
+type GatewayStatus struct {
+	Status           string           `json:"status"`
+	PauseReason      string           `json:"pause_reason,omitempty"`
+	PausedSince      string           `json:"paused_since,omitempty"`
+	PublicEndpoint   string           `json:"public_endpoint"`
+	PrivateEndpoints []EndpointStatus `json:"private_endpoints"`
+	RateLimit        RateLimitStatus  `json:"rate_limit"`
+	State            state.Snapshot   `json:"state"`
+}
+
+// The health of the endpoint of the private chain.
+type EndpointStatus struct {
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+	Height  uint64 `json:"height"`
+}
+
+func NewEndpointStatus(url string, healthy bool, height uint64) EndpointStatus {
+	var endpointStatus EndpointStatus
+
+	endpointStatus.URL = url
+	endpointStatus.Healthy = healthy
+	endpointStatus.Height = height
+
+	return endpointStatus
+}
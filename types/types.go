@@ -21,7 +21,9 @@ type App struct {
 	Viper       *viper.Viper
 	PubClient   *client.XplaClient
 	PrivClient  *client.XplaClient
+	Senders     []*client.XplaClient
 	Channels    Channels
 	HomePath    string
 	AppFilePath string
+	Output      string
 }
@@ -0,0 +1,155 @@
+package types
+
+const (
+	VerifyFieldHeight    = "height"
+	VerifyFieldHash      = "hash"
+	VerifyFieldHeader    = "header"
+	VerifyFieldMerkle    = "merkle"
+	VerifyFieldTimestamp = "timestamp"
+
+	// Fields of the tendermint header which are not matched with the recomputed hash.
+	VerifyFieldHeaderVersionBlock = "header.version_block"
+	VerifyFieldHeaderVersionApp   = "header.version_app"
+	VerifyFieldHeaderChainID      = "header.chain_id"
+	VerifyFieldHeaderLastBlockID  = "header.last_block_id"
+	VerifyFieldHeaderDataHash     = "header.data_hash"
+)
+
+// All fields which are counted by summaries of the verification.
+var VerifyFields = []string{
+	VerifyFieldHeight,
+	VerifyFieldHash,
+	VerifyFieldHeader,
+	VerifyFieldHeaderVersionBlock,
+	VerifyFieldHeaderVersionApp,
+	VerifyFieldHeaderChainID,
+	VerifyFieldHeaderLastBlockID,
+	VerifyFieldHeaderDataHash,
+	VerifyFieldMerkle,
+	VerifyFieldTimestamp,
+}
+
+// The result of comparing the recorded block info with the block of the private chain.
+type VerifyResult struct {
+	Height     string   `json:"height"`
+	Missing    bool     `json:"missing,omitempty"`
+	Mismatches []string `json:"mismatches,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+func NewVerifyResult(height string) VerifyResult {
+	var verifyResult VerifyResult
+	verifyResult.Height = height
+
+	return verifyResult
+}
+
+func (v VerifyResult) Consistent() bool {
+	return !v.Missing && len(v.Mismatches) == 0 && v.Error == ""
+}
+
+// The summary of the verification of the range of heights.
+// Failed heights are not able to be checked by errors of requests.
+type VerifySummary struct {
+	FromHeight        string         `json:"from_height"`
+	ToHeight          string         `json:"to_height"`
+	Sampled           bool           `json:"sampled"`
+	Checked           int            `json:"checked"`
+	Matched           int            `json:"matched"`
+	Mismatched        int            `json:"mismatched"`
+	MismatchedFields  map[string]int `json:"mismatched_fields"`
+	MismatchedHeights []string       `json:"mismatched_heights,omitempty"`
+	Missing           int            `json:"missing"`
+	MissingHeights    []string       `json:"missing_heights,omitempty"`
+	Failed            int            `json:"failed"`
+	FailedHeights     []string       `json:"failed_heights,omitempty"`
+}
+
+func NewVerifySummary(fromHeight, toHeight string, sampled bool) VerifySummary {
+	var verifySummary VerifySummary
+
+	verifySummary.FromHeight = fromHeight
+	verifySummary.ToHeight = toHeight
+	verifySummary.Sampled = sampled
+	verifySummary.MismatchedFields = make(map[string]int)
+
+	return verifySummary
+}
+
+func (v *VerifySummary) Add(result VerifyResult) {
+	v.Checked++
+
+	switch {
+	case result.Error != "":
+		v.Failed++
+		v.FailedHeights = append(v.FailedHeights, result.Height)
+	case result.Missing:
+		v.Missing++
+		v.MissingHeights = append(v.MissingHeights, result.Height)
+	case len(result.Mismatches) != 0:
+		v.Mismatched++
+		v.MismatchedHeights = append(v.MismatchedHeights, result.Height)
+		for _, field := range result.Mismatches {
+			v.MismatchedFields[field]++
+		}
+	default:
+		v.Matched++
+	}
+}
+
+func (v VerifySummary) Consistent() bool {
+	return v.Mismatched == 0 && v.Missing == 0 && v.Failed == 0
+}
+
+// The field of the block which is compared by the verification of the height.
+type VerifyField struct {
+	Field    string `json:"field"`
+	Private  string `json:"private"`
+	Recorded string `json:"recorded"`
+	Matched  bool   `json:"matched"`
+}
+
+// The field of the tendermint header which is compared with its own source such as the config or the previous block.
+type VerifyHeaderField struct {
+	Field    string `json:"field"`
+	Private  string `json:"private"`
+	Expected string `json:"expected"`
+	Matched  bool   `json:"matched"`
+}
+
+func NewVerifyHeaderField(field, private, expected string) VerifyHeaderField {
+	var verifyHeaderField VerifyHeaderField
+
+	verifyHeaderField.Field = field
+	verifyHeaderField.Private = private
+	verifyHeaderField.Expected = expected
+	verifyHeaderField.Matched = private == expected
+
+	return verifyHeaderField
+}
+
+// The report of the verification of the height.
+// The sink is where the block info is recorded, the contract or the memo.
+// The computed hash is the hash of the tendermint header, and it is empty for the block which is not the tendermint header.
+// Header fields are checked by their own sources only if the computed hash is not matched.
+type VerifyReport struct {
+	Height       string              `json:"height"`
+	Sink         string              `json:"sink"`
+	Consistent   bool                `json:"consistent"`
+	Missing      bool                `json:"missing"`
+	Mismatches   []string            `json:"mismatches"`
+	ComputedHash string              `json:"computed_hash,omitempty"`
+	Fields       []VerifyField       `json:"fields"`
+	HeaderFields []VerifyHeaderField `json:"header_fields,omitempty"`
+}
+
+func NewVerifyReport(height, sink string) VerifyReport {
+	var verifyReport VerifyReport
+
+	verifyReport.Height = height
+	verifyReport.Sink = sink
+	verifyReport.Mismatches = []string{}
+	verifyReport.Fields = []VerifyField{}
+
+	return verifyReport
+}
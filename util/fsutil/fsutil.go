@@ -0,0 +1,47 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write the file atomically.
+// Bytes are written to the temporary file in the same directory and it is renamed to the path,
+// so readers and the interrupted write never see the partially written file.
+// The temporary file is unique, so several writers of the same path do not conflict.
+func WriteFile(path string, bytes []byte) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = tmpFile.Write(bytes)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
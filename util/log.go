@@ -4,100 +4,57 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/Moonyongjung/xpla-anchor/state"
 	"github.com/Moonyongjung/xpla-anchor/types"
 	"github.com/logrusorgru/aurora"
 )
 
-var logIndexOnce sync.Once
-var logIndexInstance *LogIndex
+var logOutput io.Writer = os.Stdout
 
-type LogIndex struct {
-	LogDB     bool
-	LogFile   bool
-	InfoIndex string
-	ErrIndex  string
-}
-
-func InitLogIndex() *LogIndex {
-	logIndexOnce.Do(func() {
-		logIndexInstance = &LogIndex{}
-	})
-	return logIndexInstance
-}
-
-func (l *LogIndex) UseDb() {
-	l.LogDB = true
-}
-
-func (l *LogIndex) IsUseDb() bool {
-	return l.LogDB
-}
-
-func (l *LogIndex) NewErrLogIndex(Index string) {
-	temp, _ := strconv.Atoi(Index)
-	l.ErrIndex = strconv.Itoa(temp + 1)
-}
-
-func (l *LogIndex) NowErrLogIndex() string {
-	return l.ErrIndex
-}
-
-func (l *LogIndex) AddErrLogIndex() {
-	temp, _ := strconv.Atoi(l.ErrIndex)
-	l.ErrIndex = strconv.Itoa(temp + 1)
-}
-
-func (l *LogIndex) NewInfoLogIndex(Index string) {
-	temp, _ := strconv.Atoi(Index)
-	l.InfoIndex = strconv.Itoa(temp + 1)
-}
-
-func (l *LogIndex) NowInfoLogIndex() string {
-	return l.InfoIndex
-}
-
-func (l *LogIndex) AddInfoLogIndex() {
-	temp, _ := strconv.Atoi(l.InfoIndex)
-	l.InfoIndex = strconv.Itoa(temp + 1)
+// Set the writer of logs.
+// Logs are written to stderr when results of queries are printed to stdout by the structured format.
+func SetLogOutput(w io.Writer) {
+	logOutput = w
 }
 
 func LogInfo(log ...interface{}) {
 	print := logTime() + G(" Anchor   ") + ToStringTrim(log, "")
-	fmt.Println(print)
+	fmt.Fprintln(logOutput, print)
 
-	if InitLogIndex().IsUseDb() {
+	if state.Mng().IsUseDb() {
 		saveLogsDb(ToStringTrim(log, ""), 0, types.InfoLogTable)
 	}
 }
 
 func LogWait(log ...interface{}) {
 	print := logTime() + Y(" Waiting  ") + ToStringTrim(log, "")
-	fmt.Println(print)
+	fmt.Fprintln(logOutput, print)
 
-	if InitLogIndex().IsUseDb() {
+	if state.Mng().IsUseDb() {
 		saveLogsDb(ToStringTrim(log, ""), 1, types.InfoLogTable)
 	}
 }
 
 func LogWarning(log ...interface{}) {
 	print := logTime() + " " + BgR("WARNING") + "  " + ToStringTrim(log, "")
-	fmt.Println(print)
+	fmt.Fprintln(logOutput, print)
 
-	if InitLogIndex().IsUseDb() {
+	if state.Mng().IsUseDb() {
 		saveLogsDb(ToStringTrim(log, ""), 2, types.ErrLogTable)
 	}
 }
 
 func LogErr(errType types.XGoError, errDesc ...interface{}) error {
 	print := logErr("code", errType.ErrCode(), ":", errType.Desc(), "-", errDesc)
-	fmt.Println(print)
+	fmt.Fprintln(logOutput, print)
 
-	if InitLogIndex().IsUseDb() {
+	if state.Mng().IsUseDb() {
 		var log []interface{}
 		log = append(log, errType.Desc(), "-", errDesc)
 		saveLogsDb(ToStringTrim(log, ""), errType.ErrCode(), types.ErrLogTable)
@@ -127,13 +84,13 @@ func saveLogsDb(message string, code uint64, logTable string) {
 	}
 	defer dbExe.Close()
 
-	var index string
+	var index uint64
 
 	if logTable == types.ErrLogTable {
-		index = InitLogIndex().NowErrLogIndex()
+		index = state.Mng().TakeErrLogIndex()
 
 	} else if logTable == types.InfoLogTable {
-		index = InitLogIndex().NowInfoLogIndex()
+		index = state.Mng().TakeInfoLogIndex()
 
 	} else {
 		panic("invalid log table")
@@ -142,20 +99,10 @@ func saveLogsDb(message string, code uint64, logTable string) {
 	message = strings.ReplaceAll(message, "[94m", "")
 	message = strings.ReplaceAll(message, "[0m", "")
 
-	_, err = dbExe.Exec(index, code, message, time.Now())
+	_, err = dbExe.Exec(strconv.FormatUint(index, 10), code, message, time.Now())
 	if err != nil {
 		panic(err)
 	}
-
-	if logTable == types.ErrLogTable {
-		InitLogIndex().AddErrLogIndex()
-
-	} else if logTable == types.InfoLogTable {
-		InitLogIndex().AddInfoLogIndex()
-
-	} else {
-		panic("invalid log table")
-	}
 }
 
 func G(str string) string {